@@ -0,0 +1,123 @@
+package authentication
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+var _ interfaces.Authenticator = (*APIKeyAuthenticator)(nil)
+
+// APIKeyAuthenticator accepts personal API keys as bearer tokens, leaving
+// every other token to the wrapped authenticator.
+type APIKeyAuthenticator struct {
+	next    interfaces.Authenticator
+	apiKeys interfaces.APIKeyRepository
+	users   interfaces.UserRepository
+	now     func() time.Time
+}
+
+func NewAPIKeyAuthenticator(
+	authenticator interfaces.Authenticator,
+	apiKeyRepository interfaces.APIKeyRepository,
+	userRepository interfaces.UserRepository,
+) interfaces.Authenticator {
+	return &APIKeyAuthenticator{
+		next:    authenticator,
+		apiKeys: apiKeyRepository,
+		users:   userRepository,
+		now:     time.Now,
+	}
+}
+
+// GenerateToken is left to the wrapped authenticator, API keys are only
+// created through the APIKeyService.
+func (a APIKeyAuthenticator) GenerateToken(claims *dtos.TokenClaims) (string, error) {
+	return a.next.GenerateToken(claims)
+}
+
+func (a APIKeyAuthenticator) GenerateIDToken(idToken *dtos.IDToken) (string, error) {
+	return a.next.GenerateIDToken(idToken)
+}
+
+// ParseToken looks up tokens starting with models.APIKeyPrefix by their hash,
+// returning the claims of the key owner with their current roles.
+//
+// Rejected keys return one of the utils.ErrToken* errors.
+func (a APIKeyAuthenticator) ParseToken(inputToken string) (*dtos.TokenClaims, error) {
+	if !strings.HasPrefix(inputToken, models.APIKeyPrefix) {
+		return a.next.ParseToken(inputToken)
+	}
+
+	key, errC := a.apiKeys.SelectAPIKeyFromHash(utils.HashToken(inputToken))
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, utils.ErrTokenInvalid
+		}
+		return nil, errC
+	}
+
+	if key.RevokedAt != nil {
+		return nil, utils.ErrTokenRevoked
+	}
+	if key.ExpiresAt != nil && !a.now().Before(*key.ExpiresAt) {
+		return nil, utils.ErrTokenExpired
+	}
+
+	user, errC := a.users.SelectUserFromId(key.UserID)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, utils.ErrTokenInvalid
+		}
+		return nil, errC
+	}
+
+	roles, errC := a.users.SelectUserRoles(key.UserID)
+	if errC != nil {
+		return nil, errC
+	}
+
+	claims := &dtos.TokenClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Roles:    roles,
+		Scopes:   key.Scopes,
+		TokenID:  key.Prefix,
+		IssuedAt: key.CreatedAt,
+		APIKeyID: key.ID,
+	}
+	if key.ExpiresAt != nil {
+		claims.ExpiresAt = *key.ExpiresAt
+	}
+
+	return claims, nil
+}
+
+// RevokeToken revokes the API key the claims come from, or else the token.
+func (a APIKeyAuthenticator) RevokeToken(claims *dtos.TokenClaims) error {
+	if claims.APIKeyID == 0 {
+		return a.next.RevokeToken(claims)
+	}
+
+	errC := a.apiKeys.RevokeAPIKey(claims.UserID, claims.APIKeyID, a.now().UTC())
+	if errC != nil {
+		return errC
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes the tokens of the user, keeping their API keys,
+// which are revoked one by one or by DefaultTokenService.LogoutAll.
+func (a APIKeyAuthenticator) RevokeUserTokens(userId int) error {
+	return a.next.RevokeUserTokens(userId)
+}
+
+func (a APIKeyAuthenticator) JSONWebKeySet() *dtos.JSONWebKeySet {
+	return a.next.JSONWebKeySet()
+}
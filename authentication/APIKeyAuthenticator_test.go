@@ -0,0 +1,92 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+func TestAPIKeyAuthenticator_ParseToken(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	user, _ := userRepo.CreateUser(&dtos.UserWithHash{
+		UserModel: models.UserModel{UserName: "Diego", Email: "diego@mail.com"},
+		Hash:      "fb78ed1e-a121-542f-a68d-fcd21ffe83c5",
+	})
+	userRepo.AddUserRole(user.ID, models.RoleUser)
+
+	expiredAt := fixedNow.Add(-time.Minute)
+	expiresAt := fixedNow.Add(time.Hour)
+
+	apiKeyRepo := mocks.NewMockedAPIKeyRepository()
+	for _, key := range []*dtos.APIKey{
+		{UserID: user.ID, Name: "ci", Hash: utils.HashToken("sak_valid"), Scopes: []string{"users:read"}},
+		{UserID: user.ID, Name: "expiring", Hash: utils.HashToken("sak_expiring"), ExpiresAt: &expiresAt},
+		{UserID: user.ID, Name: "expired", Hash: utils.HashToken("sak_expired"), ExpiresAt: &expiredAt},
+		{UserID: user.ID, Name: "revoked", Hash: utils.HashToken("sak_revoked"), RevokedAt: &expiredAt},
+		{UserID: 42, Name: "orphan", Hash: utils.HashToken("sak_orphan")},
+	} {
+		apiKeyRepo.CreateAPIKey(key)
+	}
+
+	authenticator := NewAPIKeyAuthenticator(mocks.NewMockedAuthenticator(), apiKeyRepo, userRepo).(*APIKeyAuthenticator)
+	authenticator.now = func() time.Time { return fixedNow }
+
+	testCases := []struct {
+		token    string
+		err      error
+		apiKeyId int
+	}{
+		{"sak_valid", nil, 1},
+		{"sak_expiring", nil, 2},
+		{"sak_expired", utils.ErrTokenExpired, 0},
+		{"sak_revoked", utils.ErrTokenRevoked, 0},
+		{"sak_orphan", utils.ErrTokenInvalid, 0},
+		{"sak_unknown", utils.ErrTokenInvalid, 0},
+		{"valid-for(7)[alex@mail.com]", nil, 0},
+		{"invalid-for(7)[alex@mail.com]", utils.ErrTokenMalformed, 0},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			claims, err := authenticator.ParseToken(_case.token)
+
+			if !errors.Is(err, _case.err) {
+				t.Fatalf("Error should be '%v', got '%v'", _case.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if claims.APIKeyID != _case.apiKeyId {
+				t.Errorf("APIKeyID should be '%d', got '%d'", _case.apiKeyId, claims.APIKeyID)
+			}
+		})
+	}
+
+	claims, _ := authenticator.ParseToken("sak_valid")
+	if claims.UserID != user.ID || claims.Email != "diego@mail.com" || !claims.HasRole(models.RoleUser) || !claims.HasScope("users:read") {
+		t.Errorf("API key claims should hold the user, their roles and the key scopes, got %+v", claims)
+	}
+
+	if err := authenticator.RevokeToken(claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticator.ParseToken("sak_valid"); !errors.Is(err, utils.ErrTokenRevoked) {
+		t.Errorf("Revoking the API key claims should revoke the key, got '%v'", err)
+	}
+	if _, err := authenticator.ParseToken("sak_expiring"); err != nil {
+		t.Errorf("Revoking an API key should keep the other keys of the user, got '%v'", err)
+	}
+
+	if err := authenticator.RevokeUserTokens(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticator.ParseToken("sak_expiring"); err != nil {
+		t.Errorf("Revoking every token of the user should keep their API keys, got '%v'", err)
+	}
+}
@@ -0,0 +1,105 @@
+package authentication
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+)
+
+// defaultKeyId identifies the key derived from config.Auth.Base64TokenSeed.
+const defaultKeyId = "default"
+
+// ed25519KeyRing holds every key tokens may be verified with, and the only
+// one new tokens are signed with.
+type ed25519KeyRing struct {
+	activeId   string
+	active     ed25519.PrivateKey
+	keyIds     []string
+	publicKeys map[string]ed25519.PublicKey
+}
+
+func newEd25519KeyRing(settings *config.Auth) (*ed25519KeyRing, error) {
+	signingKeys := settings.SigningKeys
+	activeId := settings.ActiveSigningKey
+
+	if len(signingKeys) == 0 {
+		signingKeys = []config.SigningKey{{ID: defaultKeyId, Base64Seed: settings.Base64TokenSeed}}
+		activeId = defaultKeyId
+	}
+
+	ring := &ed25519KeyRing{
+		activeId:   activeId,
+		keyIds:     make([]string, 0, len(signingKeys)),
+		publicKeys: make(map[string]ed25519.PublicKey, len(signingKeys)),
+	}
+
+	for _, signingKey := range signingKeys {
+		if signingKey.ID == "" {
+			return nil, errors.New("every signing key must have an id")
+		}
+		if _, exist := ring.publicKeys[signingKey.ID]; exist {
+			return nil, fmt.Errorf("duplicated signing key id '%s'", signingKey.ID)
+		}
+
+		seed, err := base64.RawStdEncoding.DecodeString(signingKey.Base64Seed)
+		if err != nil {
+			return nil, fmt.Errorf("signing key '%s': %w", signingKey.ID, err)
+		}
+
+		privateKey := ed25519.NewKeyFromSeed(seed)
+		publicKey, ok := privateKey.Public().(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("could not parse crypto.PublicKey into an ed25519.PublicKey")
+		}
+
+		if signingKey.ID == activeId {
+			ring.active = privateKey
+		}
+
+		ring.keyIds = append(ring.keyIds, signingKey.ID)
+		ring.publicKeys[signingKey.ID] = publicKey
+	}
+
+	if ring.active == nil {
+		return nil, fmt.Errorf("active signing key '%s' is not one of the signing keys", activeId)
+	}
+
+	return ring, nil
+}
+
+// publicKey returns the verification key with the given id.
+//
+// Tokens issued before key ids existed have none, and are checked against
+// the active key.
+func (r ed25519KeyRing) publicKey(keyId string) (ed25519.PublicKey, bool) {
+	if keyId == "" {
+		keyId = r.activeId
+	}
+
+	publicKey, ok := r.publicKeys[keyId]
+
+	return publicKey, ok
+}
+
+func (r ed25519KeyRing) jsonWebKeySet() *dtos.JSONWebKeySet {
+	keySet := &dtos.JSONWebKeySet{
+		Keys: make([]dtos.JSONWebKey, 0, len(r.keyIds)),
+	}
+
+	for _, keyId := range r.keyIds {
+		keySet.Keys = append(keySet.Keys, dtos.JSONWebKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(r.publicKeys[keyId]),
+			KeyId:     keyId,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+		})
+	}
+
+	return keySet
+}
@@ -1,60 +1,257 @@
 package authentication
 
 import (
-	"crypto/ed25519"
-	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
 	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var _ interfaces.Authenticator = (*JWTEd25519Authenticator)(nil)
 
+const defaultAccessTokenLifetime = 15 * time.Minute
+
 type JWTEd25519Authenticator struct {
-	privKey ed25519.PrivateKey
-	pubKey  ed25519.PublicKey
+	keys        *ed25519KeyRing
+	settings    *config.Auth
+	revocations interfaces.TokenRevocationRepository
+	now         func() time.Time
 }
 
-func NewJWTEd25519Authenticator(settings *config.Auth) (interfaces.Authenticator, error) {
-	seed, err := base64.RawStdEncoding.DecodeString(settings.Base64TokenSeed)
-	if err != nil {
-		return nil, err
+// userClaims are the claims carried by every access token.
+type userClaims struct {
+	ID    *int     `json:"id"`
+	Email string   `json:"email"`
+	Roles []string `json:"roles,omitempty"`
+	Scope string   `json:"scope,omitempty"`
+	Sid   string   `json:"sid,omitempty"`
+	// ClientID is the OAuth client the token was issued to, as in RFC 9068
+	ClientID string `json:"client_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Validate makes the otherwise optional "exp" claim mandatory, along with
+// the "id" claim ID tokens lack.
+func (c userClaims) Validate() error {
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("%w: exp", jwt.ErrTokenRequiredClaimMissing)
+	}
+	if c.ID == nil {
+		return fmt.Errorf("%w: id", jwt.ErrTokenRequiredClaimMissing)
 	}
 
-	privateKey := ed25519.NewKeyFromSeed(seed)
-	publicKey, ok := privateKey.Public().(ed25519.PublicKey)
-	if !ok {
-		return nil, errors.New("could not parse crypto.PublicKey into an ed25519.PublicKey")
+	return nil
+}
+
+// idTokenClaims are the claims of OpenID Connect ID tokens.
+type idTokenClaims struct {
+	Nonce             string `json:"nonce,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func init() {
+	// Revoking every token of a user must tell apart the tokens issued
+	// earlier in the second of the revocation from the ones issued later
+	jwt.TimePrecision = time.Microsecond
+}
+
+func NewJWTEd25519Authenticator(
+	settings *config.Auth,
+	tokenRevocationRepository interfaces.TokenRevocationRepository,
+) (interfaces.Authenticator, error) {
+	keys, err := newEd25519KeyRing(settings)
+	if err != nil {
+		return nil, err
 	}
 
 	return &JWTEd25519Authenticator{
-		privKey: privateKey,
-		pubKey:  publicKey,
+		keys:        keys,
+		settings:    settings,
+		revocations: tokenRevocationRepository,
+		now:         time.Now,
 	}, nil
 }
 
-func (a JWTEd25519Authenticator) GenerateToken(id int, email string) (string, error) {
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodEdDSA,
-		jwt.MapClaims{
-			"id":    id,
-			"email": email,
+// GenerateToken signs a token for the UserID, Email, Roles, Scopes,
+// SessionID and ClientID of claims with the active signing key, filling in
+// their TokenID, IssuedAt and ExpiresAt.
+//
+// Tokens of OAuth clients acting for no user have the client as subject.
+//
+// A preset ExpiresAt replaces the configured access token lifetime.
+func (a JWTEd25519Authenticator) GenerateToken(claims *dtos.TokenClaims) (string, error) {
+	tokenId, err := utils.NewRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := a.now()
+
+	expiresAt := now.Add(a.accessTokenLifetime())
+	if !claims.ExpiresAt.IsZero() {
+		expiresAt = claims.ExpiresAt
+	}
+
+	userId := claims.UserID
+
+	jwtClaims := userClaims{
+		ID:       &userId,
+		Email:    claims.Email,
+		Roles:    claims.Roles,
+		Scope:    strings.Join(claims.Scopes, " "),
+		Sid:      claims.SessionID,
+		ClientID: claims.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
+			Subject:   strconv.Itoa(claims.UserID),
+			Issuer:    a.settings.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
+	}
+
+	if claims.IsClient() {
+		jwtClaims.Subject = claims.ClientID
+	}
+
+	if a.settings.Audience != "" {
+		jwtClaims.Audience = jwt.ClaimStrings{a.settings.Audience}
+	}
+
+	token, err := a.sign(jwtClaims)
+	if err != nil {
+		return "", err
+	}
+
+	claims.TokenID = tokenId
+	claims.IssuedAt = jwtClaims.IssuedAt.UTC()
+	claims.ExpiresAt = jwtClaims.ExpiresAt.UTC()
+
+	return token, nil
+}
+
+// GenerateIDToken signs an OpenID Connect ID token for the client of the
+// idToken Audience, living as long as access tokens do.
+//
+// ID tokens lack the "id" claim, so they're never accepted as access tokens.
+func (a JWTEd25519Authenticator) GenerateIDToken(idToken *dtos.IDToken) (string, error) {
+	tokenId, err := utils.NewRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := a.now()
+
+	return a.sign(idTokenClaims{
+		Nonce:             idToken.Nonce,
+		Email:             idToken.Email,
+		EmailVerified:     idToken.EmailVerified,
+		PreferredUsername: idToken.PreferredUsername,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
+			Subject:   idToken.Subject,
+			Issuer:    a.settings.Issuer,
+			Audience:  jwt.ClaimStrings{idToken.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.accessTokenLifetime())),
+		},
+	})
+}
+
+// ParseToken verifies the token signature, expiration, issuer and audience,
+// tolerating a clock skew of settings.ClockSkewLeeway, and then checks the
+// token was not revoked.
+//
+// Rejected tokens return one of the utils.ErrToken* errors.
+func (a JWTEd25519Authenticator) ParseToken(inputToken string) (*dtos.TokenClaims, error) {
+	claims := new(userClaims)
+
+	token, err := jwt.ParseWithClaims(
+		inputToken,
+		claims,
+		a.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithTimeFunc(a.now),
+		jwt.WithLeeway(a.settings.ClockSkewLeeway),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(a.settings.Issuer),
+		jwt.WithAudience(a.settings.Audience),
 	)
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	if !token.Valid {
+		return nil, utils.ErrTokenInvalid
+	}
 
-	return token.SignedString(a.privKey)
+	parsed := &dtos.TokenClaims{
+		UserID:    *claims.ID,
+		Email:     claims.Email,
+		Roles:     claims.Roles,
+		Scopes:    strings.Fields(claims.Scope),
+		TokenID:   claims.RegisteredClaims.ID,
+		ExpiresAt: claims.ExpiresAt.UTC(),
+		SessionID: claims.Sid,
+		ClientID:  claims.ClientID,
+	}
+	if claims.IssuedAt != nil {
+		parsed.IssuedAt = claims.IssuedAt.UTC()
+	}
+
+	revoked, errC := a.revocations.IsTokenRevoked(parsed.TokenID, parsed.UserID, parsed.IssuedAt)
+	if errC != nil {
+		return nil, errC
+	}
+	if revoked {
+		return nil, utils.ErrTokenRevoked
+	}
+
+	return parsed, nil
 }
 
-func (a JWTEd25519Authenticator) IsTokenValid(inputToken string) bool {
-	token, err := jwt.Parse(inputToken, a.keyFunc)
-	if err != nil || !token.Valid {
-		return false
+// RevokeToken revokes the token until its expiration.
+func (a JWTEd25519Authenticator) RevokeToken(claims *dtos.TokenClaims) error {
+	errC := a.revocations.RevokeToken(claims.TokenID, claims.ExpiresAt, a.now().UTC())
+	if errC != nil {
+		return errC
 	}
 
-	return true
+	return nil
+}
+
+// RevokeUserTokens revokes every token issued to the user before now, to the
+// microsecond tokens are issued at.
+func (a JWTEd25519Authenticator) RevokeUserTokens(userId int) error {
+	errC := a.revocations.RevokeUserTokens(userId, a.now().UTC().Truncate(jwt.TimePrecision))
+	if errC != nil {
+		return errC
+	}
+
+	return nil
+}
+
+// JSONWebKeySet returns the public keys tokens are verified with.
+func (a JWTEd25519Authenticator) JSONWebKeySet() *dtos.JSONWebKeySet {
+	return a.keys.jsonWebKeySet()
+}
+
+// sign signs claims with the active signing key, naming it in the header.
+func (a JWTEd25519Authenticator) sign(claims jwt.Claims) (string, error) {
+	unsignedToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	unsignedToken.Header["kid"] = a.keys.activeId
+
+	return unsignedToken.SignedString(a.keys.active)
 }
 
 func (a JWTEd25519Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
@@ -62,5 +259,34 @@ func (a JWTEd25519Authenticator) keyFunc(token *jwt.Token) (interface{}, error)
 		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
 	}
 
-	return a.pubKey, nil
+	keyId, _ := token.Header["kid"].(string)
+
+	publicKey, ok := a.keys.publicKey(keyId)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key '%s'", keyId)
+	}
+
+	return publicKey, nil
+}
+
+func (a JWTEd25519Authenticator) accessTokenLifetime() time.Duration {
+	if a.settings.AccessTokenLifetime <= 0 {
+		return defaultAccessTokenLifetime
+	}
+
+	return a.settings.AccessTokenLifetime
+}
+
+// tokenError translates jwt parsing errors into utils.ErrToken* errors.
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return fmt.Errorf("%w: %w", utils.ErrTokenMalformed, err)
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return fmt.Errorf("%w: %w", utils.ErrTokenBadSignature, err)
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return fmt.Errorf("%w: %w", utils.ErrTokenExpired, err)
+	default:
+		return fmt.Errorf("%w: %w", utils.ErrTokenInvalid, err)
+	}
 }
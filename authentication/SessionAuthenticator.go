@@ -0,0 +1,75 @@
+package authentication
+
+import (
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+var _ interfaces.Authenticator = (*SessionAuthenticator)(nil)
+
+// SessionAuthenticator rejects the tokens of revoked sessions, which the
+// wrapped authenticator would accept until they expire.
+type SessionAuthenticator struct {
+	next     interfaces.Authenticator
+	sessions interfaces.SessionRepository
+}
+
+func NewSessionAuthenticator(
+	authenticator interfaces.Authenticator,
+	sessionRepository interfaces.SessionRepository,
+) interfaces.Authenticator {
+	return &SessionAuthenticator{
+		next:     authenticator,
+		sessions: sessionRepository,
+	}
+}
+
+func (a SessionAuthenticator) GenerateToken(claims *dtos.TokenClaims) (string, error) {
+	return a.next.GenerateToken(claims)
+}
+
+func (a SessionAuthenticator) GenerateIDToken(idToken *dtos.IDToken) (string, error) {
+	return a.next.GenerateIDToken(idToken)
+}
+
+// ParseToken parses the token with the wrapped authenticator, returning
+// utils.ErrTokenRevoked when its session was revoked or no longer exists.
+func (a SessionAuthenticator) ParseToken(inputToken string) (*dtos.TokenClaims, error) {
+	claims, err := a.next.ParseToken(inputToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.SessionID == "" {
+		return claims, nil
+	}
+
+	session, errC := a.sessions.SelectSession(claims.SessionID)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, utils.ErrTokenRevoked
+		}
+		return nil, errC
+	}
+
+	if session.RevokedAt != nil || session.UserID != claims.UserID {
+		return nil, utils.ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (a SessionAuthenticator) RevokeToken(claims *dtos.TokenClaims) error {
+	return a.next.RevokeToken(claims)
+}
+
+func (a SessionAuthenticator) RevokeUserTokens(userId int) error {
+	return a.next.RevokeUserTokens(userId)
+}
+
+func (a SessionAuthenticator) JSONWebKeySet() *dtos.JSONWebKeySet {
+	return a.next.JSONWebKeySet()
+}
@@ -0,0 +1,90 @@
+package authorization
+
+import (
+	"sort"
+
+	"github.com/d1360-64rc14/simple-api/models"
+)
+
+const (
+	PermUsersRead   = "users:read"
+	PermUsersList   = "users:list"
+	PermUsersUpdate = "users:update"
+	PermUsersDelete = "users:delete"
+	PermRolesManage = "roles:manage"
+
+	PermLockoutsManage     = "lockouts:manage"
+	PermOAuthClientsManage = "oauth_clients:manage"
+)
+
+var rolePermissions = map[string][]string{
+	models.RoleAdmin: {
+		PermUsersRead,
+		PermUsersList,
+		PermUsersUpdate,
+		PermUsersDelete,
+		PermRolesManage,
+		PermLockoutsManage,
+		PermOAuthClientsManage,
+	},
+	models.RoleUser: {
+		PermUsersRead,
+		PermUsersUpdate,
+		PermUsersDelete,
+	},
+	models.RoleReadOnly: {
+		PermUsersRead,
+	},
+}
+
+// IsKnownRole checks if role is one of the models.Role* roles.
+func IsKnownRole(role string) bool {
+	_, ok := rolePermissions[role]
+
+	return ok
+}
+
+// IsKnownPermission checks if permission is granted by any role.
+func IsKnownPermission(permission string) bool {
+	for _, permissions := range rolePermissions {
+		for _, p := range permissions {
+			if p == permission {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// KnownPermissions returns every permission granted by any role, sorted.
+func KnownPermissions() []string {
+	known := make(map[string]bool)
+	for _, permissions := range rolePermissions {
+		for _, p := range permissions {
+			known[p] = true
+		}
+	}
+
+	permissions := make([]string, 0, len(known))
+	for p := range known {
+		permissions = append(permissions, p)
+	}
+
+	sort.Strings(permissions)
+
+	return permissions
+}
+
+// HasPermission checks if any of the roles grants permission.
+func HasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package authorization
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/models"
+)
+
+func TestHasPermission(t *testing.T) {
+	testCases := []struct {
+		roles      []string
+		permission string
+		allowed    bool
+	}{
+		{[]string{models.RoleAdmin}, PermUsersList, true},
+		{[]string{models.RoleAdmin}, PermRolesManage, true},
+		{[]string{models.RoleUser}, PermUsersDelete, true},
+		{[]string{models.RoleUser}, PermUsersList, false},
+		{[]string{models.RoleUser}, PermRolesManage, false},
+		{[]string{models.RoleAdmin}, PermLockoutsManage, true},
+		{[]string{models.RoleUser}, PermLockoutsManage, false},
+		{[]string{models.RoleAdmin}, PermOAuthClientsManage, true},
+		{[]string{models.RoleUser}, PermOAuthClientsManage, false},
+		{[]string{models.RoleReadOnly}, PermUsersRead, true},
+		{[]string{models.RoleReadOnly}, PermUsersUpdate, false},
+		{[]string{models.RoleReadOnly, models.RoleUser}, PermUsersUpdate, true},
+		{[]string{"superuser"}, PermUsersRead, false},
+		{[]string{}, PermUsersRead, false},
+		{nil, PermUsersRead, false},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			allowed := HasPermission(_case.roles, _case.permission)
+
+			if allowed != _case.allowed {
+				t.Errorf("Expected %t, got %t", _case.allowed, allowed)
+			}
+		})
+	}
+}
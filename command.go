@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+)
+
+const usage = "usage: simple-api [migrate up|down [steps]|status | promote email]"
+
+// runCommand runs the command of args instead of serving the API.
+func runCommand(migrator interfaces.Migrator, userRepository interfaces.UserRepository, args []string) error {
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "migrate":
+		return migrate(migrator, args[1:])
+	case "promote":
+		if len(args) > 2 {
+			return errors.New(usage)
+		}
+
+		return promote(userRepository, args[1])
+	}
+
+	return errors.New(usage)
+}
+
+// migrate applies, undoes or lists the migrations as args tell.
+func migrate(migrator interfaces.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		logMigrations("applied", applied)
+		return err
+
+	case "down":
+		steps := 1
+
+		if len(args) > 1 {
+			var err error
+
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(usage)
+			}
+		}
+
+		reverted, err := migrator.Down(steps)
+		logMigrations("reverted", reverted)
+		return err
+
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range status {
+			appliedAt := "pending"
+			if migration.AppliedAt != nil {
+				appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%04d  %-30s %s\n", migration.Version, migration.Name, appliedAt)
+		}
+
+		return nil
+	}
+
+	return errors.New(usage)
+}
+
+// promote grants the admin role to the user of email, which is the only way
+// to get the first administrator.
+func promote(userRepository interfaces.UserRepository, email string) error {
+	user, errC := userRepository.SelectUserFromEmail(email)
+	if errC != nil {
+		return errC
+	}
+
+	errC = userRepository.AddUserRole(user.ID, models.RoleAdmin)
+	if errC != nil {
+		return errC
+	}
+
+	log.Printf("granted the %s role to user %d", models.RoleAdmin, user.ID)
+
+	return nil
+}
+
+func logMigrations(action string, migrations []*dtos.Migration) {
+	for _, migration := range migrations {
+		log.Printf("%s migration %04d_%s", action, migration.Version, migration.Name)
+	}
+}
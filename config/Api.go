@@ -1,6 +1,17 @@
 package config
 
+import "fmt"
+
 type Api struct {
-	BaseUrl  string `yaml:"baseUrl"`
-	Protocol string `yaml:"procotol"`
+	BaseUrl    string     `yaml:"baseUrl"`
+	Protocol   string     `yaml:"protocol"`
+	Pagination Pagination `yaml:"pagination"`
+	// TrustedProxies are the addresses or CIDRs of the proxies whose
+	// X-Forwarded-For header tells the client IP. None are trusted when empty
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// URL returns the absolute URL of path on this API.
+func (a Api) URL(path string) string {
+	return fmt.Sprintf("%s://%s%s", a.Protocol, a.BaseUrl, path)
 }
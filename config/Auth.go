@@ -1,6 +1,24 @@
 package config
 
+import "time"
+
 type Auth struct {
-	Base64TokenSeed string `json:"base64TokenSeed"`
-	BCryptCost      int    `json:"bcryptCost"`
+	// Base64TokenSeed is used as the "default" signing key when SigningKeys is empty
+	Base64TokenSeed            string          `yaml:"base64TokenSeed"`
+	SigningKeys                []SigningKey    `yaml:"signingKeys"`
+	ActiveSigningKey           string          `yaml:"activeSigningKey"`
+	BCryptCost                 int             `yaml:"bcryptCost"`
+	PasswordHashing            PasswordHashing `yaml:"passwordHashing"`
+	Issuer                     string          `yaml:"issuer"`
+	Audience                   string          `yaml:"audience"`
+	AccessTokenLifetime        time.Duration   `yaml:"accessTokenLifetime"`
+	RefreshTokenLifetime       time.Duration   `yaml:"refreshTokenLifetime"`
+	PasswordResetTokenLifetime time.Duration   `yaml:"passwordResetTokenLifetime"`
+	// RequireVerifiedEmail rejects logins of users who didn't verify their email
+	RequireVerifiedEmail           bool          `yaml:"requireVerifiedEmail"`
+	EmailVerificationTokenLifetime time.Duration `yaml:"emailVerificationTokenLifetime"`
+	MFATokenLifetime               time.Duration `yaml:"mfaTokenLifetime"`
+	Lockout                        Lockout       `yaml:"lockout"`
+	OAuth                          OAuth         `yaml:"oauth"`
+	ClockSkewLeeway                time.Duration `yaml:"clockSkewLeeway"`
 }
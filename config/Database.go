@@ -1,8 +1,20 @@
 package config
 
+const (
+	DriverMySQL    = "mysql"
+	DriverRamSQL   = "ramsql"
+	DriverPostgres = "postgres"
+)
+
 type Database struct {
+	// Driver picks the database, DriverMySQL when empty
+	Driver       string `yaml:"driver"`
 	Address      string `yaml:"address"`
 	DBName       string `yaml:"dbName"`
 	Username     string `yaml:"username"`
 	RootPassword string `yaml:"rootPassword"`
+	// SSLMode is the sslmode of PostgreSQL connections, "require" when empty
+	SSLMode string `yaml:"sslMode"`
+	// AutoMigrate applies the pending migrations when the API starts
+	AutoMigrate bool `yaml:"autoMigrate"`
 }
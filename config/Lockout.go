@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// Lockout configures how failed logins lock accounts and client IPs out.
+//
+// Each failure past a threshold doubles the lockout Duration, up to
+// MaxDuration. Failures older than Window are forgotten.
+type Lockout struct {
+	AccountThreshold int           `yaml:"accountThreshold"`
+	IPThreshold      int           `yaml:"ipThreshold"`
+	Window           time.Duration `yaml:"window"`
+	Duration         time.Duration `yaml:"duration"`
+	MaxDuration      time.Duration `yaml:"maxDuration"`
+}
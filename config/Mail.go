@@ -0,0 +1,8 @@
+package config
+
+type Mail struct {
+	// Sender is either "stdout" or "file"
+	Sender   string `yaml:"sender"`
+	From     string `yaml:"from"`
+	FilePath string `yaml:"filePath"`
+}
@@ -0,0 +1,7 @@
+package config
+
+import "time"
+
+type OAuth struct {
+	AuthorizationCodeLifetime time.Duration `yaml:"authorizationCodeLifetime"`
+}
@@ -0,0 +1,6 @@
+package config
+
+type Pagination struct {
+	// Base64CursorKey signs the cursors of the list endpoints, 32 bytes wide
+	Base64CursorKey string `yaml:"base64CursorKey"`
+}
@@ -0,0 +1,14 @@
+package config
+
+type PasswordHashing struct {
+	// Algorithm new hashes are made with, "bcrypt" or "argon2id".
+	// Hashes made with the other one are upgraded on login.
+	Algorithm string   `yaml:"algorithm"`
+	Argon2id  Argon2id `yaml:"argon2id"`
+}
+
+type Argon2id struct {
+	MemoryKiB   uint32 `yaml:"memoryKiB"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+}
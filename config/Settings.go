@@ -10,6 +10,7 @@ type Settings struct {
 	Api      Api      `yaml:"api"`
 	Database Database `yaml:"database"`
 	Auth     Auth     `yaml:"auth"`
+	Mail     Mail     `yaml:"mail"`
 }
 
 func NewSettings(filename string) (*Settings, error) {
@@ -0,0 +1,6 @@
+package config
+
+type SigningKey struct {
+	ID         string `yaml:"id"`
+	Base64Seed string `yaml:"base64Seed"`
+}
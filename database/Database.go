@@ -0,0 +1,22 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+// New opens the database of the configured driver, MySQL when none is.
+func New(databaseSettings *config.Database) (interfaces.Database, error) {
+	switch databaseSettings.Driver {
+	case config.DriverMySQL, "":
+		return NewMySQL(databaseSettings)
+	case config.DriverPostgres:
+		return NewPostgres(databaseSettings)
+	case config.DriverRamSQL:
+		return NewRamMySQL(databaseSettings)
+	}
+
+	return nil, fmt.Errorf("unknown database driver '%s'", databaseSettings.Driver)
+}
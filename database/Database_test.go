@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/config"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		driver string
+		want   string
+		err    string
+	}{
+		{"", config.DriverMySQL, ""},
+		{config.DriverMySQL, config.DriverMySQL, ""},
+		{config.DriverPostgres, config.DriverPostgres, ""},
+		{config.DriverRamSQL, config.DriverRamSQL, ""},
+		{"sqlite", "", "unknown database driver 'sqlite'"},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			// Opening doesn't connect, so no server is needed
+			db, err := New(&config.Database{
+				Driver:  _case.driver,
+				Address: "localhost:5432",
+				DBName:  "simple_api",
+			})
+			if _case.err != "" {
+				if err == nil || err.Error() != _case.err {
+					t.Errorf("Error should be '%s', got '%v'", _case.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if db.Driver() != _case.want {
+				t.Errorf("Driver should be '%s', got '%s'", _case.want, db.Driver())
+			}
+		})
+	}
+}
+
+func TestRebind(t *testing.T) {
+	testCases := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1;", "SELECT 1;"},
+		{"SELECT * FROM users WHERE id = ?;", "SELECT * FROM users WHERE id = $1;"},
+		{"UPDATE users SET email = ?, verified_at = ? WHERE id = ?;", "UPDATE users SET email = $1, verified_at = $2 WHERE id = $3;"},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			got := Rebind(_case.query)
+			if got != _case.want {
+				t.Errorf("Query should be '%s', got '%s'", _case.want, got)
+			}
+		})
+	}
+}
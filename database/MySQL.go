@@ -33,14 +33,14 @@ func NewMySQL(databaseSettings *config.Database) (interfaces.Database, error) {
 
 func (d *MySQL) setup() (err error) {
 	dbSource := fmt.Sprintf(
-		"%s:%s@tcp(%s)/%s",
+		"%s:%s@tcp(%s)/%s?parseTime=true",
 		d.settings.Username,
 		d.settings.RootPassword,
 		d.settings.Address,
 		d.settings.DBName,
 	)
 
-	d.database, err = sql.Open("mysql", dbSource)
+	d.database, err = sql.Open(config.DriverMySQL, dbSource)
 	if err != nil {
 		return err
 	}
@@ -52,6 +52,10 @@ func (d MySQL) Settings() *config.Database {
 	return d.settings
 }
 
+func (d MySQL) Driver() string {
+	return config.DriverMySQL
+}
+
 func (d MySQL) DB() *sql.DB {
 	return d.database
 }
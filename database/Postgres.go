@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+// Postgres implements Database
+var _ interfaces.Database = (*Postgres)(nil)
+
+type Postgres struct {
+	settings *config.Database
+	database *sql.DB
+}
+
+func NewPostgres(databaseSettings *config.Database) (interfaces.Database, error) {
+	postgres := &Postgres{
+		settings: databaseSettings,
+	}
+
+	err := postgres.setup()
+	if err != nil {
+		return nil, err
+	}
+
+	return postgres, nil
+}
+
+func (d *Postgres) setup() (err error) {
+	dbSource := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.settings.Username, d.settings.RootPassword),
+		Host:   d.settings.Address,
+		Path:   "/" + d.settings.DBName,
+	}
+
+	// Timestamps are read in UTC, as the MySQL driver does
+	query := url.Values{"timezone": {"UTC"}}
+	if d.settings.SSLMode != "" {
+		query.Set("sslmode", d.settings.SSLMode)
+	}
+	dbSource.RawQuery = query.Encode()
+
+	d.database, err = sql.Open(config.DriverPostgres, dbSource.String())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d Postgres) Settings() *config.Database {
+	return d.settings
+}
+
+func (d Postgres) Driver() string {
+	return config.DriverPostgres
+}
+
+func (d Postgres) DB() *sql.DB {
+	return d.database
+}
+
+func (d Postgres) Close() error {
+	return d.database.Close()
+}
+
+// Rebind numbers the "?" placeholders of query as PostgreSQL wants them,
+// "$1", "$2" and so on.
+func Rebind(query string) string {
+	var rebound strings.Builder
+	placeholder := 0
+
+	for _, r := range query {
+		if r != '?' {
+			rebound.WriteRune(r)
+			continue
+		}
+
+		placeholder++
+		rebound.WriteString("$" + strconv.Itoa(placeholder))
+	}
+
+	return rebound.String()
+}
@@ -31,7 +31,7 @@ func NewRamMySQL(databaseSettings *config.Database) (interfaces.Database, error)
 }
 
 func (d *RamMySQL) setup() (err error) {
-	d.database, err = sql.Open("ramsql", d.Settings().DBName)
+	d.database, err = sql.Open(config.DriverRamSQL, d.Settings().DBName)
 	if err != nil {
 		return err
 	}
@@ -43,6 +43,10 @@ func (d RamMySQL) Settings() *config.Database {
 	return d.settings
 }
 
+func (d RamMySQL) Driver() string {
+	return config.DriverRamSQL
+}
+
 func (d RamMySQL) DB() *sql.DB {
 	return d.database
 }
@@ -0,0 +1,20 @@
+package dtos
+
+import "time"
+
+// APIKey is the stored form of a personal API key. Only the Prefix of the key
+// is kept in clear.
+//
+// Scopes restrict the key to some of the permissions of its user, keys
+// without scopes have every permission of their user.
+type APIKey struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"userId"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	RevokedAt *time.Time `json:"-"`
+}
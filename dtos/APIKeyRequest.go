@@ -0,0 +1,9 @@
+package dtos
+
+import "time"
+
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Scopes    []string   `json:"scopes" binding:"max=20,dive,max=50"`
+}
@@ -0,0 +1,19 @@
+package dtos
+
+import "time"
+
+// AuthorizationCode is the stored form of an issued OAuth authorization
+// code, bound to the PKCE CodeChallenge of the request asking for it. The
+// Nonce of the request is echoed in the ID token.
+type AuthorizationCode struct {
+	ID            int
+	Hash          string
+	ClientID      string
+	UserID        int
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	Nonce         string
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+}
@@ -0,0 +1,7 @@
+package dtos
+
+// ClientInfo describes where a login comes from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
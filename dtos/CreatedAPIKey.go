@@ -0,0 +1,7 @@
+package dtos
+
+// CreatedAPIKey is the only time the whole Key is shown to its user.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
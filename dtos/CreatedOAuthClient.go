@@ -0,0 +1,8 @@
+package dtos
+
+// CreatedOAuthClient is the only time the ClientSecret of a confidential
+// client is shown.
+type CreatedOAuthClient struct {
+	OAuthClient
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
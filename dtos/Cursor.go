@@ -0,0 +1,12 @@
+package dtos
+
+// Cursor is a position in a list ordered by Sort, right after the item whose
+// sort key is Key and whose tie-breaking id is ID.
+//
+// Backward cursors point at the items before the position instead.
+type Cursor struct {
+	Sort     string `json:"s"`
+	Key      string `json:"k,omitempty"`
+	ID       int    `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
@@ -0,0 +1,5 @@
+package dtos
+
+type EmailChange struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+}
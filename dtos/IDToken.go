@@ -0,0 +1,9 @@
+package dtos
+
+// IDToken is what an OpenID Connect ID token tells the client it was issued
+// to about the user, echoing the Nonce of the authorization request.
+type IDToken struct {
+	OIDCClaims
+	Audience string
+	Nonce    string
+}
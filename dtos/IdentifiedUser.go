@@ -1,8 +1,14 @@
 package dtos
 
-import "github.com/d1360-64rc14/simple-api/models"
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/models"
+)
 
 type IdentifiedUser struct {
 	ID int `json:"id"`
 	models.UserModel
+	EmailVerified bool       `json:"emailVerified"`
+	LastLoginAt   *time.Time `json:"lastLoginAt"`
 }
@@ -0,0 +1,16 @@
+package dtos
+
+import "time"
+
+// LoginAttempt is an entry of the login history. UserID is nil for emails
+// without account.
+type LoginAttempt struct {
+	ID        int       `json:"id"`
+	UserID    *int      `json:"userId"`
+	Email     string    `json:"email"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	Succeeded bool      `json:"succeeded"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package dtos
+
+// LoginHistoryQuery selects, from the query string, a page of a login
+// history, newest first.
+//
+// Cursor, verified apart from the rest of the query, replaces Offset.
+type LoginHistoryQuery struct {
+	Limit  int     `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int     `form:"offset" binding:"omitempty,min=0"`
+	Cursor *Cursor `form:"-"`
+}
+
+// LoginHistorySort is the only order of the login history, which its cursors
+// are made for.
+const LoginHistorySort = "-createdAt"
+
+// Normalize fills in the defaults of the missing parameters.
+func (q *LoginHistoryQuery) Normalize() {
+	if q.Limit <= 0 {
+		q.Limit = defaultPageSize
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+}
@@ -0,0 +1,12 @@
+package dtos
+
+import "time"
+
+// LoginLockout counts the recent failed logins of an account or client IP.
+type LoginLockout struct {
+	Kind          string     `json:"kind"`
+	Subject       string     `json:"subject"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil"`
+}
@@ -2,5 +2,9 @@ package dtos
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email,max=100"`
-	Password string `json:"password" binding:"required,min=8,max=72,ascii"`
+	Password string `json:"password" binding:"required,min=8,max=128,ascii"`
+	// ClientIP and UserAgent are filled by the controller, never read from
+	// the body
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
 }
@@ -0,0 +1,5 @@
+package dtos
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"max=100"`
+}
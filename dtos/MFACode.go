@@ -0,0 +1,6 @@
+package dtos
+
+// MFACode is either a TOTP code or a recovery code.
+type MFACode struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
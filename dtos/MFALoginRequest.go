@@ -0,0 +1,10 @@
+package dtos
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required,max=1024"`
+	MFACode
+	// ClientIP and UserAgent are filled by the controller, never read from
+	// the body
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
@@ -0,0 +1,7 @@
+package dtos
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
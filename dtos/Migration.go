@@ -0,0 +1,10 @@
+package dtos
+
+import "time"
+
+// Migration is a step of the database schema, AppliedAt nil while pending.
+type Migration struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
@@ -0,0 +1,7 @@
+package dtos
+
+// OAuthAuthorization is where the app should send the user agent back to,
+// carrying the authorization code and state.
+type OAuthAuthorization struct {
+	RedirectTo string `json:"redirectTo"`
+}
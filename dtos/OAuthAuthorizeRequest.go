@@ -0,0 +1,14 @@
+package dtos
+
+// OAuthAuthorizeRequest asks for an authorization code on behalf of the
+// authenticated user, with the parameters of RFC 6749 and RFC 7636.
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required,max=64"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"max=512"`
+	Scope               string `form:"scope" json:"scope" binding:"max=1024"`
+	State               string `form:"state" json:"state" binding:"max=512"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required,min=43,max=128"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Nonce               string `form:"nonce" json:"nonce" binding:"max=512"`
+}
@@ -0,0 +1,18 @@
+package dtos
+
+import "time"
+
+// OAuthClient is an application registered to obtain tokens through OAuth.
+//
+// Public clients, such as single page and mobile apps, can't keep a secret
+// and have no SecretHash. Scopes bound those the client may ask for.
+type OAuthClient struct {
+	ID           string    `json:"clientId"`
+	Name         string    `json:"name"`
+	SecretHash   string    `json:"-"`
+	Public       bool      `json:"public"`
+	RedirectURIs []string  `json:"redirectUris"`
+	GrantTypes   []string  `json:"grantTypes"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,9 @@
+package dtos
+
+type OAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	Public       bool     `json:"public"`
+	RedirectURIs []string `json:"redirectUris" binding:"max=10,dive,max=512"`
+	GrantTypes   []string `json:"grantTypes" binding:"required,min=1,max=2,dive,max=50"`
+	Scopes       []string `json:"scopes" binding:"max=20,dive,max=50"`
+}
@@ -0,0 +1,19 @@
+package dtos
+
+import "strings"
+
+// OAuthError is the error response of the OAuth endpoints.
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// NewOAuthError splits an error formatted as "<error code>: <description>".
+func NewOAuthError(err error) *OAuthError {
+	code, description, _ := strings.Cut(err.Error(), ": ")
+
+	return &OAuthError{
+		Error:            code,
+		ErrorDescription: description,
+	}
+}
@@ -0,0 +1,15 @@
+package dtos
+
+// OAuthIntrospection describes a token as RFC 7662 does, inactive tokens
+// only having Active set.
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+}
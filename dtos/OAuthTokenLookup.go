@@ -0,0 +1,10 @@
+package dtos
+
+// OAuthTokenLookup is the form posted to the introspection and revocation
+// endpoints.
+type OAuthTokenLookup struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
@@ -0,0 +1,15 @@
+package dtos
+
+// OAuthTokenRequest is the form posted to the token endpoint.
+//
+// Clients may authenticate with HTTP Basic instead of ClientID and
+// ClientSecret.
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
@@ -0,0 +1,10 @@
+package dtos
+
+// OIDCClaims are the standard OpenID Connect claims about a user. Claims the
+// scopes of the token don't grant are left out.
+type OIDCClaims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+}
@@ -0,0 +1,16 @@
+package dtos
+
+const defaultPageSize = 20
+
+// LinkedPage is a slice of a larger list, either starting Offset items into
+// it or at a cursor, along with the links to the pages around it.
+type LinkedPage[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+}
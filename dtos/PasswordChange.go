@@ -0,0 +1,6 @@
+package dtos
+
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword" binding:"required,max=128"`
+	NewPassword     string `json:"newPassword" binding:"required,min=8,max=128,ascii"`
+}
@@ -0,0 +1,6 @@
+package dtos
+
+type PasswordReset struct {
+	Token       string `json:"token" binding:"required,max=100"`
+	NewPassword string `json:"newPassword" binding:"required,min=8,max=128,ascii"`
+}
@@ -0,0 +1,5 @@
+package dtos
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,5 @@
+package dtos
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required,max=100"`
+}
@@ -0,0 +1,16 @@
+package dtos
+
+import "time"
+
+// RefreshToken is the stored form of an issued refresh token.
+//
+// Every rotation of a refresh token keeps its FamilyID.
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	FamilyID  string
+	Hash      string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
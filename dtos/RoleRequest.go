@@ -0,0 +1,5 @@
+package dtos
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required,max=20"`
+}
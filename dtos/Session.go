@@ -0,0 +1,19 @@
+package dtos
+
+import "time"
+
+// Session is a login of the user, living as long as its refresh token
+// family. Its ID is the family ID, carried by the access tokens as "sid".
+//
+// Current is only set when listing, for the session of the caller.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int        `json:"userId"`
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current"`
+}
@@ -0,0 +1,6 @@
+package dtos
+
+type TOTPEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
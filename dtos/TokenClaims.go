@@ -0,0 +1,55 @@
+package dtos
+
+import "time"
+
+// TokenClaims is what an access token says about its bearer.
+//
+// Claims of API keys have their APIKeyID set, and their Scopes restrict the
+// permissions of the user roles. Access tokens issued on login carry the
+// SessionID they belong to, and those issued through OAuth the ClientID they
+// were issued to, their Scopes restricting them likewise. OAuth clients
+// acting for themselves have no UserID.
+type TokenClaims struct {
+	UserID    int       `json:"userId"`
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles"`
+	Scopes    []string  `json:"scopes"`
+	TokenID   string    `json:"tokenId"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	SessionID string    `json:"sessionId,omitempty"`
+	APIKeyID  int       `json:"apiKeyId,omitempty"`
+	ClientID  string    `json:"clientId,omitempty"`
+}
+
+func (c TokenClaims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c TokenClaims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsDelegated tells if the claims come from an API key or an OAuth client,
+// acting for their user with at most their Scopes.
+func (c TokenClaims) IsDelegated() bool {
+	return c.APIKeyID != 0 || c.ClientID != ""
+}
+
+// IsClient tells if the claims are of an OAuth client acting for itself,
+// rather than for a user.
+func (c TokenClaims) IsClient() bool {
+	return c.ClientID != "" && c.UserID == 0
+}
@@ -1,6 +1,9 @@
 package dtos
 
+// TokenResponse holds either the user tokens, or an MFAToken to be exchanged
+// for them along with a second factor code.
 type TokenResponse struct {
-	Token string `json:"token"`
-	// TODO: RefreshToken
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	MFAToken     string `json:"mfaToken,omitempty"`
 }
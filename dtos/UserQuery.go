@@ -0,0 +1,33 @@
+package dtos
+
+// UserQuery selects, from the query string, a page of the users matching its
+// filters.
+//
+// Sort is the field to order by, descending when prefixed by "-". Cursor,
+// verified apart from the rest of the query, replaces Offset.
+type UserQuery struct {
+	Limit          int     `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset         int     `form:"offset" binding:"omitempty,min=0"`
+	Sort           string  `form:"sort" binding:"omitempty,oneof=id -id username -username"`
+	EmailDomain    string  `form:"email_domain" binding:"omitempty,max=255"`
+	UsernamePrefix string  `form:"username_prefix" binding:"omitempty,max=255"`
+	Cursor         *Cursor `form:"-"`
+}
+
+const defaultUserSort = "id"
+
+// Normalize fills in the defaults of the missing parameters.
+func (q *UserQuery) Normalize() {
+	if q.Limit <= 0 {
+		q.Limit = defaultPageSize
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Sort == "" && q.Cursor != nil {
+		q.Sort = q.Cursor.Sort
+	}
+	if q.Sort == "" {
+		q.Sort = defaultUserSort
+	}
+}
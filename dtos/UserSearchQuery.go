@@ -0,0 +1,30 @@
+package dtos
+
+import (
+	"strings"
+	"unicode"
+)
+
+// UserSearchQuery looks up, from the query string, at most Limit users by a
+// partial username or email in Q.
+type UserSearchQuery struct {
+	Q     string `form:"q" binding:"required,max=100"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+const defaultSearchLimit = 10
+
+// Normalize fills in the defaults of the missing parameters.
+func (q *UserSearchQuery) Normalize() {
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+}
+
+// Terms are the words of Q, split at anything but letters and digits, so
+// "diego@mail" looks for both "diego" and "mail".
+func (q UserSearchQuery) Terms() []string {
+	return strings.FieldsFunc(strings.ToLower(q.Q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,13 @@
+package dtos
+
+import "time"
+
+// UserTOTP is the stored TOTP enrolment of a user, enabled once confirmed.
+//
+// LastUsedStep is the time step of the last accepted code, refusing replays.
+type UserTOTP struct {
+	UserID       int
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+}
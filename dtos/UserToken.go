@@ -0,0 +1,17 @@
+package dtos
+
+import "time"
+
+// UserToken is the stored form of a single-use token sent to a user, such
+// as a password reset one.
+//
+// Payload holds purpose specific data, like the new address of an email change.
+type UserToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	Hash      string
+	Payload   string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
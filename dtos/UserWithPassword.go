@@ -4,5 +4,5 @@ import "github.com/d1360-64rc14/simple-api/models"
 
 type UserWithPassword struct {
 	models.UserModel
-	Password string `json:"password" binding:"required,min=8,max=72,ascii"`
+	Password string `json:"password" binding:"required,min=8,max=128,ascii"`
 }
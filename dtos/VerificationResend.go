@@ -0,0 +1,5 @@
+package dtos
+
+type VerificationResend struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+}
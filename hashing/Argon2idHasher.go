@@ -0,0 +1,143 @@
+package hashing
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idHasher implements PasswordHasher
+var _ interfaces.PasswordHasher = (*Argon2idHasher)(nil)
+
+const (
+	argon2idPrefix     = "$argon2id$"
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+var ErrInvalidArgon2idHash = errors.New("invalid argon2id PHC string")
+
+// Argon2idHasher makes PHC formatted hashes like
+// "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>".
+type Argon2idHasher struct {
+	params argon2idParams
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func NewArgon2idHasher(memoryKiB uint32, iterations uint32, parallelism uint8) *Argon2idHasher {
+	return &Argon2idHasher{
+		params: argon2idParams{
+			memory:      memoryKiB,
+			iterations:  iterations,
+			parallelism: parallelism,
+		},
+	}
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey(
+		[]byte(password),
+		salt,
+		h.params.iterations,
+		h.params.memory,
+		h.params.parallelism,
+		argon2idKeyLength,
+	)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.params.memory,
+		h.params.iterations,
+		h.params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h Argon2idHasher) Verify(password string, hash string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := argon2.IDKey(
+		[]byte(password),
+		salt,
+		params.iterations,
+		params.memory,
+		params.parallelism,
+		uint32(len(key)),
+	)
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+// NeedsRehash checks if the hash was made with other parameters.
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, key, err := decodeArgon2id(hash)
+
+	return err != nil || params != h.params || len(key) != argon2idKeyLength
+}
+
+// MaxPasswordLength is 0, argon2id hashes passwords of any length.
+func (Argon2idHasher) MaxPasswordLength() int {
+	return 0
+}
+
+// owns checks if the hash was made by argon2id.
+func (Argon2idHasher) owns(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func decodeArgon2id(hash string) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidArgon2idHash
+	}
+
+	var version int
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidArgon2idHash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil {
+		return params, nil, nil, ErrInvalidArgon2idHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidArgon2idHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidArgon2idHash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,67 @@
+package hashing
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BCryptHasher implements PasswordHasher
+var _ interfaces.PasswordHasher = (*BCryptHasher)(nil)
+
+// bcryptMaxPasswordLength is the length in bytes of the longest password
+// bcrypt hashes, longer ones are refused.
+const bcryptMaxPasswordLength = 72
+
+// BCryptHasher makes "$2a$<cost>$..." hashes. Passwords are limited to
+// 72 bytes.
+type BCryptHasher struct {
+	cost int
+}
+
+func NewBCryptHasher(cost int) *BCryptHasher {
+	return &BCryptHasher{
+		cost: cost,
+	}
+}
+
+func (h BCryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h BCryptHasher) Verify(password string, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// NeedsRehash checks if the hash was made with another cost.
+func (h BCryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost != h.cost
+}
+
+func (BCryptHasher) MaxPasswordLength() int {
+	return bcryptMaxPasswordLength
+}
+
+// owns checks if the hash was made by bcrypt.
+func (BCryptHasher) owns(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,95 @@
+package hashing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+// DefaultPasswordHasher implements PasswordHasher
+var _ interfaces.PasswordHasher = (*DefaultPasswordHasher)(nil)
+
+const (
+	AlgorithmBCrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+type algorithmHasher interface {
+	interfaces.PasswordHasher
+	owns(hash string) bool
+}
+
+// DefaultPasswordHasher hashes with the configured algorithm, while still
+// verifying hashes made by any of the supported ones.
+type DefaultPasswordHasher struct {
+	preferred algorithmHasher
+	hashers   []algorithmHasher
+}
+
+func NewDefaultPasswordHasher(settings *config.Auth) (interfaces.PasswordHasher, error) {
+	bcryptHasher := NewBCryptHasher(settings.BCryptCost)
+
+	argon2idSettings := settings.PasswordHashing.Argon2id
+	argon2idHasher := NewArgon2idHasher(
+		argon2idSettings.MemoryKiB,
+		argon2idSettings.Iterations,
+		argon2idSettings.Parallelism,
+	)
+
+	hasher := &DefaultPasswordHasher{
+		hashers: []algorithmHasher{bcryptHasher, argon2idHasher},
+	}
+
+	switch settings.PasswordHashing.Algorithm {
+	case AlgorithmBCrypt, "":
+		hasher.preferred = bcryptHasher
+	case AlgorithmArgon2id:
+		if argon2idSettings.MemoryKiB == 0 || argon2idSettings.Iterations == 0 || argon2idSettings.Parallelism == 0 {
+			return nil, errors.New("argon2id memoryKiB, iterations and parallelism must be set")
+		}
+		hasher.preferred = argon2idHasher
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm '%s'", settings.PasswordHashing.Algorithm)
+	}
+
+	return hasher, nil
+}
+
+func (h DefaultPasswordHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h DefaultPasswordHasher) Verify(password string, hash string) (bool, error) {
+	hasher := h.ownerOf(hash)
+	if hasher == nil {
+		return false, ErrUnknownHashFormat
+	}
+
+	return hasher.Verify(password, hash)
+}
+
+// NeedsRehash checks if the hash was made by another algorithm or with other
+// parameters than the configured ones.
+func (h DefaultPasswordHasher) NeedsRehash(hash string) bool {
+	return !h.preferred.owns(hash) || h.preferred.NeedsRehash(hash)
+}
+
+// MaxPasswordLength returns the length in bytes of the longest password the
+// configured algorithm hashes, 0 when there is no limit.
+func (h DefaultPasswordHasher) MaxPasswordLength() int {
+	return h.preferred.MaxPasswordLength()
+}
+
+func (h DefaultPasswordHasher) ownerOf(hash string) algorithmHasher {
+	for _, hasher := range h.hashers {
+		if hasher.owns(hash) {
+			return hasher
+		}
+	}
+
+	return nil
+}
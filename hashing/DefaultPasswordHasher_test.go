@@ -0,0 +1,135 @@
+package hashing
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/config"
+)
+
+func newTestHasher(t *testing.T, algorithm string, cost int, memory uint32) *DefaultPasswordHasher {
+	hasher, err := NewDefaultPasswordHasher(&config.Auth{
+		BCryptCost: cost,
+		PasswordHashing: config.PasswordHashing{
+			Algorithm: algorithm,
+			Argon2id:  config.Argon2id{MemoryKiB: memory, Iterations: 1, Parallelism: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hasher.(*DefaultPasswordHasher)
+}
+
+func TestNewDefaultPasswordHasher_InvalidSettings(t *testing.T) {
+	testCases := []config.PasswordHashing{
+		{Algorithm: "md5"},
+		{Algorithm: AlgorithmArgon2id},
+		{Algorithm: AlgorithmArgon2id, Argon2id: config.Argon2id{MemoryKiB: 1024, Iterations: 1}},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			_, err := NewDefaultPasswordHasher(&config.Auth{BCryptCost: 4, PasswordHashing: _case})
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestHashAndVerify(t *testing.T) {
+	testCases := []struct {
+		algorithm string
+		prefix    string
+	}{
+		{"", "$2a$04$"},
+		{AlgorithmBCrypt, "$2a$04$"},
+		{AlgorithmArgon2id, "$argon2id$v=19$m=1024,t=1,p=1$"},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			hasher := newTestHasher(t, _case.algorithm, 4, 1024)
+
+			hash, err := hasher.Hash("myPassword!")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(hash, _case.prefix) {
+				t.Errorf("Hash should start with '%s', got '%s'", _case.prefix, hash)
+			}
+
+			ok, err := hasher.Verify("myPassword!", hash)
+			if err != nil || !ok {
+				t.Errorf("Correct password should be verified, got %t and '%v'", ok, err)
+			}
+
+			ok, err = hasher.Verify("myPassword?", hash)
+			if err != nil || ok {
+				t.Errorf("Wrong password should not be verified, got %t and '%v'", ok, err)
+			}
+
+			if hasher.NeedsRehash(hash) {
+				t.Error("Fresh hash should not need a rehash")
+			}
+		})
+	}
+}
+
+func TestVerify_AnySupportedAlgorithm(t *testing.T) {
+	bcryptHash, _ := newTestHasher(t, AlgorithmBCrypt, 4, 1024).Hash("myPassword!")
+	argon2idHash, _ := newTestHasher(t, AlgorithmArgon2id, 4, 1024).Hash("myPassword!")
+
+	for _, algorithm := range []string{AlgorithmBCrypt, AlgorithmArgon2id} {
+		hasher := newTestHasher(t, algorithm, 5, 2048)
+
+		for _, hash := range []string{bcryptHash, argon2idHash} {
+			ok, err := hasher.Verify("myPassword!", hash)
+			if err != nil || !ok {
+				t.Errorf("%s hasher should verify '%s', got %t and '%v'", algorithm, hash, ok, err)
+			}
+		}
+	}
+
+	_, err := newTestHasher(t, AlgorithmArgon2id, 4, 1024).Verify("myPassword!", "$1$md5$hash")
+	if err != ErrUnknownHashFormat {
+		t.Errorf("Expected '%s', got '%v'", ErrUnknownHashFormat, err)
+	}
+
+	_, err = newTestHasher(t, AlgorithmArgon2id, 4, 1024).Verify("myPassword!", "$argon2id$v=19$m=1024$salt$key")
+	if err != ErrInvalidArgon2idHash {
+		t.Errorf("Expected '%s', got '%v'", ErrInvalidArgon2idHash, err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHash, _ := newTestHasher(t, AlgorithmBCrypt, 4, 1024).Hash("myPassword!")
+	argon2idHash, _ := newTestHasher(t, AlgorithmArgon2id, 4, 1024).Hash("myPassword!")
+
+	testCases := []struct {
+		hasher      *DefaultPasswordHasher
+		hash        string
+		needsRehash bool
+	}{
+		{newTestHasher(t, AlgorithmBCrypt, 4, 1024), bcryptHash, false},
+		{newTestHasher(t, AlgorithmBCrypt, 5, 1024), bcryptHash, true},
+		{newTestHasher(t, AlgorithmBCrypt, 4, 1024), argon2idHash, true},
+		{newTestHasher(t, AlgorithmArgon2id, 4, 1024), argon2idHash, false},
+		{newTestHasher(t, AlgorithmArgon2id, 4, 2048), argon2idHash, true},
+		{newTestHasher(t, AlgorithmArgon2id, 4, 1024), bcryptHash, true},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			needsRehash := _case.hasher.NeedsRehash(_case.hash)
+
+			if needsRehash != _case.needsRehash {
+				t.Errorf("Expected %t, got %t", _case.needsRehash, needsRehash)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(key *dtos.APIKey) (*dtos.APIKey, *utils.ErrorCode)
+	SelectAPIKeyFromHash(hash string) (*dtos.APIKey, *utils.ErrorCode)
+	SelectUserAPIKeys(userId int) ([]*dtos.APIKey, *utils.ErrorCode)
+	RevokeAPIKey(userId int, id int, revokedAt time.Time) *utils.ErrorCode
+	RevokeUserAPIKeys(userId int, revokedAt time.Time) *utils.ErrorCode
+}
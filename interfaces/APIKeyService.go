@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type APIKeyService interface {
+	CreateAPIKey(userId int, request *dtos.APIKeyRequest) (*dtos.CreatedAPIKey, *utils.ErrorCode)
+	SelectUserAPIKeys(userId int) ([]*dtos.APIKey, *utils.ErrorCode)
+	RevokeAPIKey(userId int, id int) *utils.ErrorCode
+}
@@ -1,6 +1,12 @@
 package interfaces
 
+import "github.com/d1360-64rc14/simple-api/dtos"
+
 type Authenticator interface {
-	GenerateToken(id int, email string) (string, error)
-	IsTokenValid(inputToken string) bool
+	GenerateToken(claims *dtos.TokenClaims) (string, error)
+	GenerateIDToken(idToken *dtos.IDToken) (string, error)
+	ParseToken(inputToken string) (*dtos.TokenClaims, error)
+	RevokeToken(claims *dtos.TokenClaims) error
+	RevokeUserTokens(userId int) error
+	JSONWebKeySet() *dtos.JSONWebKeySet
 }
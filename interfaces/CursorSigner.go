@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/d1360-64rc14/simple-api/dtos"
+
+type CursorSigner interface {
+	Sign(cursor *dtos.Cursor) (string, error)
+	Verify(token string) (*dtos.Cursor, error)
+}
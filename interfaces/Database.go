@@ -8,6 +8,8 @@ import (
 
 type Database interface {
 	Settings() *config.Database
+	// Driver is the name of the SQL driver, such as config.DriverMySQL
+	Driver() string
 	DB() *sql.DB
 	Close() error
 }
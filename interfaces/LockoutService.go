@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type LockoutService interface {
+	CheckLogin(email string, clientIP string) *utils.ErrorCode
+	RecordFailedLogin(email string, clientIP string) *utils.ErrorCode
+	RecordSuccessfulLogin(email string) *utils.ErrorCode
+	SelectActiveLockouts() ([]*dtos.LoginLockout, *utils.ErrorCode)
+	ClearLockout(kind string, subject string) *utils.ErrorCode
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type LoginHistoryRepository interface {
+	CreateLoginAttempt(attempt *dtos.LoginAttempt) *utils.ErrorCode
+	SelectUserLoginAttempts(userId int, query *dtos.LoginHistoryQuery) ([]*dtos.LoginAttempt, int, *utils.ErrorCode)
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type LoginLockoutRepository interface {
+	SelectLoginLockout(kind string, subject string) (*dtos.LoginLockout, *utils.ErrorCode)
+	SelectActiveLoginLockouts(now time.Time) ([]*dtos.LoginLockout, *utils.ErrorCode)
+	UpdateLoginLockout(kind string, subject string, now time.Time, update func(lockout *dtos.LoginLockout)) *utils.ErrorCode
+	RemoveLoginLockout(kind string, subject string) *utils.ErrorCode
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type MFARepository interface {
+	SelectUserTOTP(userId int) (*dtos.UserTOTP, *utils.ErrorCode)
+	SaveUserTOTP(userId int, secret string) *utils.ErrorCode
+	ConfirmUserTOTP(userId int, confirmedAt time.Time) *utils.ErrorCode
+	UseTOTPStep(userId int, step int64) (bool, *utils.ErrorCode)
+	RemoveUserTOTP(userId int) *utils.ErrorCode
+	ReplaceRecoveryCodes(userId int, hashes []string) *utils.ErrorCode
+	UseRecoveryCode(userId int, hash string, usedAt time.Time) (bool, *utils.ErrorCode)
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type MFAService interface {
+	EnrollTOTP(id int) (*dtos.TOTPEnrolment, *utils.ErrorCode)
+	ConfirmTOTP(id int, code string) (*dtos.RecoveryCodes, *utils.ErrorCode)
+	DisableTOTP(id int, code string) *utils.ErrorCode
+	IsTOTPEnabled(id int) (bool, *utils.ErrorCode)
+	IssueMFAToken(user *dtos.IdentifiedUser) (*dtos.TokenResponse, *utils.ErrorCode)
+	CompleteLogin(request *dtos.MFALoginRequest) (*dtos.TokenResponse, *utils.ErrorCode)
+}
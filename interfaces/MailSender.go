@@ -0,0 +1,7 @@
+package interfaces
+
+import "github.com/d1360-64rc14/simple-api/dtos"
+
+type MailSender interface {
+	SendMail(mail *dtos.Mail) error
+}
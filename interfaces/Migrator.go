@@ -0,0 +1,9 @@
+package interfaces
+
+import "github.com/d1360-64rc14/simple-api/dtos"
+
+type Migrator interface {
+	Up() ([]*dtos.Migration, error)
+	Down(steps int) ([]*dtos.Migration, error)
+	Status() ([]*dtos.Migration, error)
+}
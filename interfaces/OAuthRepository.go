@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type OAuthRepository interface {
+	CreateOAuthClient(client *dtos.OAuthClient) *utils.ErrorCode
+	SelectOAuthClient(clientId string) (*dtos.OAuthClient, *utils.ErrorCode)
+	SelectOAuthClients() ([]*dtos.OAuthClient, *utils.ErrorCode)
+	RemoveOAuthClient(clientId string) *utils.ErrorCode
+	CreateAuthorizationCode(code *dtos.AuthorizationCode) *utils.ErrorCode
+	SelectAuthorizationCodeFromHash(hash string) (*dtos.AuthorizationCode, *utils.ErrorCode)
+	UseAuthorizationCode(id int, usedAt time.Time) (bool, *utils.ErrorCode)
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type OAuthService interface {
+	RegisterClient(request *dtos.OAuthClientRequest) (*dtos.CreatedOAuthClient, *utils.ErrorCode)
+	SelectClients() ([]*dtos.OAuthClient, *utils.ErrorCode)
+	RemoveClient(clientId string) *utils.ErrorCode
+	Authorize(claims *dtos.TokenClaims, request *dtos.OAuthAuthorizeRequest) (*dtos.OAuthAuthorization, *utils.ErrorCode)
+	Token(request *dtos.OAuthTokenRequest) (*dtos.OAuthTokenResponse, *utils.ErrorCode)
+	Introspect(request *dtos.OAuthTokenLookup) (*dtos.OAuthIntrospection, *utils.ErrorCode)
+	Revoke(request *dtos.OAuthTokenLookup) *utils.ErrorCode
+	UserInfo(claims *dtos.TokenClaims) (*dtos.OIDCClaims, *utils.ErrorCode)
+}
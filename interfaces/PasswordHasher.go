@@ -0,0 +1,8 @@
+package interfaces
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password string, hash string) (bool, error)
+	NeedsRehash(hash string) bool
+	MaxPasswordLength() int
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(token *dtos.RefreshToken) *utils.ErrorCode
+	SelectRefreshTokenFromHash(hash string) (*dtos.RefreshToken, *utils.ErrorCode)
+	UseRefreshToken(id int, usedAt time.Time) (bool, *utils.ErrorCode)
+	RevokeRefreshTokenFamily(familyId string, revokedAt time.Time) *utils.ErrorCode
+	RevokeUserRefreshTokens(userId int, revokedAt time.Time) *utils.ErrorCode
+}
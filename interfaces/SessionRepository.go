@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type SessionRepository interface {
+	CreateSession(session *dtos.Session) *utils.ErrorCode
+	SelectSession(id string) (*dtos.Session, *utils.ErrorCode)
+	SelectUserSessions(userId int, now time.Time) ([]*dtos.Session, *utils.ErrorCode)
+	TouchSession(id string, lastSeenAt time.Time, expiresAt time.Time) *utils.ErrorCode
+	RevokeSession(userId int, id string, revokedAt time.Time) *utils.ErrorCode
+	RevokeUserSessions(userId int, revokedAt time.Time) *utils.ErrorCode
+}
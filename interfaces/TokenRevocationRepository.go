@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type TokenRevocationRepository interface {
+	RevokeToken(tokenId string, expiresAt time.Time, now time.Time) *utils.ErrorCode
+	RevokeUserTokens(userId int, issuedBefore time.Time) *utils.ErrorCode
+	IsTokenRevoked(tokenId string, userId int, issuedAt time.Time) (bool, *utils.ErrorCode)
+}
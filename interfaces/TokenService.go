@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type TokenService interface {
+	IssueTokens(user *dtos.IdentifiedUser, client *dtos.ClientInfo) (*dtos.TokenResponse, *utils.ErrorCode)
+	RefreshTokens(refreshToken string) (*dtos.TokenResponse, *utils.ErrorCode)
+	Logout(claims *dtos.TokenClaims, refreshToken string) *utils.ErrorCode
+	LogoutAll(userId int) *utils.ErrorCode
+	SelectUserSessions(userId int) ([]*dtos.Session, *utils.ErrorCode)
+	RevokeSession(userId int, sessionId string) *utils.ErrorCode
+}
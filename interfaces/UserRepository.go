@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"time"
+
 	"github.com/d1360-64rc14/simple-api/dtos"
 	"github.com/d1360-64rc14/simple-api/utils"
 )
@@ -13,7 +15,16 @@ type UserRepository interface {
 	SelectUserHashFromId(id int) (string, *utils.ErrorCode)
 	SelectCompleteUserFromId(id int) (*dtos.IdentifiedUserWithHash, *utils.ErrorCode)
 	SelectAllUsers() ([]*dtos.IdentifiedUser, *utils.ErrorCode)
+	SelectUsers(query *dtos.UserQuery) ([]*dtos.IdentifiedUser, int, *utils.ErrorCode)
+	SearchUsers(query *dtos.UserSearchQuery) ([]*dtos.IdentifiedUser, *utils.ErrorCode)
 	RemoveUser(id int) *utils.ErrorCode
 	UserExist(id int) (bool, *utils.ErrorCode)
 	UpdateUsername(id int, newUsername string) *utils.ErrorCode
+	UpdateUserHash(id int, newHash string) *utils.ErrorCode
+	VerifyUserEmail(id int, verifiedAt time.Time) *utils.ErrorCode
+	UpdateUserEmail(id int, newEmail string, verifiedAt time.Time) *utils.ErrorCode
+	UpdateUserLastLogin(id int, lastLoginAt time.Time) *utils.ErrorCode
+	SelectUserRoles(id int) ([]string, *utils.ErrorCode)
+	AddUserRole(id int, role string) *utils.ErrorCode
+	RemoveUserRole(id int, role string) *utils.ErrorCode
 }
@@ -11,8 +11,21 @@ type UserService interface {
 	SelectUserHashFromId(id int) (string, *utils.ErrorCode)
 	SelectCompleteUserFromId(id int) (*dtos.IdentifiedUserWithHash, *utils.ErrorCode)
 	SelectAllUsers() ([]*dtos.IdentifiedUser, *utils.ErrorCode)
+	SelectUsers(query *dtos.UserQuery) (*dtos.LinkedPage[*dtos.IdentifiedUser], *utils.ErrorCode)
+	SearchUsers(query *dtos.UserSearchQuery) ([]*dtos.IdentifiedUser, *utils.ErrorCode)
 	RemoveUser(id int) *utils.ErrorCode
 	UpdateUser(id int, newUserData *dtos.UserUpdate) *utils.ErrorCode
+	ChangePassword(id int, passwordChange *dtos.PasswordChange) *utils.ErrorCode
+	ForgotPassword(passwordForgot *dtos.PasswordForgot) *utils.ErrorCode
+	ResetPassword(passwordReset *dtos.PasswordReset) *utils.ErrorCode
+	VerifyEmail(token string) *utils.ErrorCode
+	ResendVerification(verificationResend *dtos.VerificationResend) *utils.ErrorCode
+	RequestEmailChange(id int, emailChange *dtos.EmailChange) *utils.ErrorCode
+	ConfirmEmailChange(token string) *utils.ErrorCode
 	AuthenticateUser(email string) (string, *utils.ErrorCode)
 	LoginUser(request *dtos.LoginRequest) (*dtos.TokenResponse, *utils.ErrorCode)
+	SelectUserLogins(id int, query *dtos.LoginHistoryQuery) (*dtos.LinkedPage[*dtos.LoginAttempt], *utils.ErrorCode)
+	SelectUserRoles(id int) ([]string, *utils.ErrorCode)
+	GrantRole(id int, role string) *utils.ErrorCode
+	RevokeRole(id int, role string) *utils.ErrorCode
 }
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+type UserTokenRepository interface {
+	CreateUserToken(token *dtos.UserToken) *utils.ErrorCode
+	SelectUserTokenFromHash(purpose string, hash string) (*dtos.UserToken, *utils.ErrorCode)
+	UseUserToken(id int, usedAt time.Time) (bool, *utils.ErrorCode)
+	InvalidateUserTokens(userId int, purpose string, invalidatedAt time.Time) *utils.ErrorCode
+}
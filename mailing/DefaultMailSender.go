@@ -0,0 +1,29 @@
+package mailing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+const (
+	SenderStdout = "stdout"
+	SenderFile   = "file"
+)
+
+// NewDefaultMailSender returns the mail sender chosen in the settings.
+func NewDefaultMailSender(settings *config.Mail) (interfaces.MailSender, error) {
+	switch settings.Sender {
+	case SenderStdout, "":
+		return NewStdoutMailSender(settings.From), nil
+	case SenderFile:
+		if settings.FilePath == "" {
+			return nil, errors.New("mail filePath must be set for the file sender")
+		}
+		return NewFileMailSender(settings.FilePath, settings.From)
+	default:
+		return nil, fmt.Errorf("unknown mail sender '%s'", settings.Sender)
+	}
+}
@@ -0,0 +1,62 @@
+package mailing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+// WriterMailSender implements MailSender
+var _ interfaces.MailSender = (*WriterMailSender)(nil)
+
+// WriterMailSender writes mails as plain text instead of delivering them,
+// which is enough for local testing.
+type WriterMailSender struct {
+	mu     sync.Mutex
+	writer io.Writer
+	from   string
+	now    func() time.Time
+}
+
+func NewWriterMailSender(writer io.Writer, from string) *WriterMailSender {
+	return &WriterMailSender{
+		writer: writer,
+		from:   from,
+		now:    time.Now,
+	}
+}
+
+func NewStdoutMailSender(from string) *WriterMailSender {
+	return NewWriterMailSender(os.Stdout, from)
+}
+
+// NewFileMailSender appends mails to the given file, creating it if needed.
+func NewFileMailSender(filename string, from string) (*WriterMailSender, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriterMailSender(file, from), nil
+}
+
+func (s *WriterMailSender) SendMail(mail *dtos.Mail) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := fmt.Fprintf(s.writer,
+		"From: %s\nTo: %s\nDate: %s\nSubject: %s\n\n%s\n\n",
+		s.from,
+		mail.To,
+		s.now().Format(time.RFC1123Z),
+		mail.Subject,
+		mail.Body,
+	)
+
+	return err
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/d1360-64rc14/simple-api/authentication"
 	"github.com/d1360-64rc14/simple-api/config"
 	"github.com/d1360-64rc14/simple-api/database"
+	"github.com/d1360-64rc14/simple-api/hashing"
 	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/mailing"
+	"github.com/d1360-64rc14/simple-api/migrations"
+	"github.com/d1360-64rc14/simple-api/pagination"
 	"github.com/d1360-64rc14/simple-api/repositories"
 	"github.com/d1360-64rc14/simple-api/routers"
 	v1 "github.com/d1360-64rc14/simple-api/routers/v1"
@@ -17,26 +22,152 @@ func main() {
 	settings, err := config.NewSettings("settings.yaml")
 	fatalErr(err)
 
-	database, err := database.NewMySQL(&settings.Database)
+	database, err := database.New(&settings.Database)
 	fatalErr(err)
 
-	authenticator, err := authentication.NewJWTEd25519Authenticator(&settings.Auth)
+	migrator, err := migrations.NewSQLMigrator(database)
 	fatalErr(err)
 
-	userRepo, err := repositories.NewMySQLUserRepository(database)
+	repos := repositoryConstructorsOf(database)
+
+	userRepo, err := repos.user(database)
+	fatalErr(err)
+
+	if len(os.Args) > 1 {
+		fatalErr(runCommand(migrator, userRepo, os.Args[1:]))
+		return
+	}
+
+	if settings.Database.AutoMigrate {
+		applied, err := migrator.Up()
+		fatalErr(err)
+		logMigrations("applied", applied)
+	}
+
+	tokenRevocationRepo, err := repos.tokenRevocation(database)
+	fatalErr(err)
+
+	jwtAuthenticator, err := authentication.NewJWTEd25519Authenticator(&settings.Auth, tokenRevocationRepo)
+	fatalErr(err)
+
+	apiKeyRepo, err := repos.apiKey(database)
 	fatalErr(err)
 
-	userService := services.NewDefaultUserService(userRepo, authenticator, settings)
-	userController := v1.NewDefaultUserController(userService, userRepo, settings)
+	sessionRepo, err := repos.session(database)
+	fatalErr(err)
+
+	sessionAuthenticator := authentication.NewSessionAuthenticator(jwtAuthenticator, sessionRepo)
+	authenticator := authentication.NewAPIKeyAuthenticator(sessionAuthenticator, apiKeyRepo, userRepo)
+
+	refreshTokenRepo, err := repos.refreshToken(database)
+	fatalErr(err)
+
+	userTokenRepo, err := repos.userToken(database)
+	fatalErr(err)
+
+	mfaRepo, err := repos.mfa(database)
+	fatalErr(err)
+
+	loginLockoutRepo, err := repos.loginLockout(database)
+	fatalErr(err)
+
+	loginHistoryRepo, err := repos.loginHistory(database)
+	fatalErr(err)
+
+	oauthRepo, err := repos.oauth(database)
+	fatalErr(err)
+
+	passwordHasher, err := hashing.NewDefaultPasswordHasher(&settings.Auth)
+	fatalErr(err)
+
+	mailSender, err := mailing.NewDefaultMailSender(&settings.Mail)
+	fatalErr(err)
+
+	cursorSigner, err := pagination.NewHMACCursorSigner(&settings.Api.Pagination)
+	fatalErr(err)
+
+	tokenService := services.NewDefaultTokenService(refreshTokenRepo, sessionRepo, userRepo, apiKeyRepo, authenticator, settings)
+	lockoutService := services.NewDefaultLockoutService(loginLockoutRepo, settings)
+	apiKeyService := services.NewDefaultAPIKeyService(apiKeyRepo, settings)
+	oauthService := services.NewDefaultOAuthService(oauthRepo, userRepo, authenticator, settings)
+	mfaService := services.NewDefaultMFAService(mfaRepo, userRepo, loginHistoryRepo, authenticator, tokenService, lockoutService, settings)
+	userService := services.NewDefaultUserService(userRepo, userTokenRepo, loginHistoryRepo, authenticator, tokenService, passwordHasher, mfaService, lockoutService, mailSender, cursorSigner, settings)
+	userController := v1.NewDefaultUserController(userService, userRepo, authenticator, cursorSigner, settings)
+	tokenController := v1.NewDefaultTokenController(tokenService, userRepo, authenticator, settings)
+	mfaController := v1.NewDefaultMFAController(mfaService, userRepo, authenticator, settings)
+	lockoutController := v1.NewDefaultLockoutController(lockoutService, authenticator, settings)
+	apiKeyController := v1.NewDefaultAPIKeyController(apiKeyService, userRepo, authenticator, settings)
+	sessionController := v1.NewDefaultSessionController(tokenService, userRepo, authenticator, settings)
+	oauthController := v1.NewDefaultOAuthController(oauthService, authenticator, settings)
 
 	controllers := []interfaces.RouteController{
 		userController,
+		tokenController,
+		mfaController,
+		lockoutController,
+		apiKeyController,
+		sessionController,
+		oauthController,
 	}
 
-	v1router := routers.NewDefaultV1Router("/api", controllers)
+	rootControllers := []interfaces.RouteController{
+		routers.NewDefaultWellKnownController(authenticator, settings, "/api/v1"),
+	}
+
+	v1router := routers.NewDefaultV1Router("/api", controllers, rootControllers)
+
+	// Client IPs key the login lockouts, so forwarded ones are only believed
+	// from the configured proxies
+	fatalErr(v1router.Engine().SetTrustedProxies(settings.Api.TrustedProxies))
+
 	v1router.Engine().Run(settings.Api.BaseUrl)
 }
 
+// repositoryConstructors build the repositories speaking the SQL of a
+// database driver.
+type repositoryConstructors struct {
+	user            func(interfaces.Database) (interfaces.UserRepository, error)
+	tokenRevocation func(interfaces.Database) (interfaces.TokenRevocationRepository, error)
+	apiKey          func(interfaces.Database) (interfaces.APIKeyRepository, error)
+	session         func(interfaces.Database) (interfaces.SessionRepository, error)
+	refreshToken    func(interfaces.Database) (interfaces.RefreshTokenRepository, error)
+	userToken       func(interfaces.Database) (interfaces.UserTokenRepository, error)
+	mfa             func(interfaces.Database) (interfaces.MFARepository, error)
+	loginLockout    func(interfaces.Database) (interfaces.LoginLockoutRepository, error)
+	loginHistory    func(interfaces.Database) (interfaces.LoginHistoryRepository, error)
+	oauth           func(interfaces.Database) (interfaces.OAuthRepository, error)
+}
+
+func repositoryConstructorsOf(database interfaces.Database) *repositoryConstructors {
+	if database.Driver() == config.DriverPostgres {
+		return &repositoryConstructors{
+			user:            repositories.NewPostgresUserRepository,
+			tokenRevocation: repositories.NewPostgresTokenRevocationRepository,
+			apiKey:          repositories.NewPostgresAPIKeyRepository,
+			session:         repositories.NewPostgresSessionRepository,
+			refreshToken:    repositories.NewPostgresRefreshTokenRepository,
+			userToken:       repositories.NewPostgresUserTokenRepository,
+			mfa:             repositories.NewPostgresMFARepository,
+			loginLockout:    repositories.NewPostgresLoginLockoutRepository,
+			loginHistory:    repositories.NewPostgresLoginHistoryRepository,
+			oauth:           repositories.NewPostgresOAuthRepository,
+		}
+	}
+
+	return &repositoryConstructors{
+		user:            repositories.NewMySQLUserRepository,
+		tokenRevocation: repositories.NewMySQLTokenRevocationRepository,
+		apiKey:          repositories.NewMySQLAPIKeyRepository,
+		session:         repositories.NewMySQLSessionRepository,
+		refreshToken:    repositories.NewMySQLRefreshTokenRepository,
+		userToken:       repositories.NewMySQLUserTokenRepository,
+		mfa:             repositories.NewMySQLMFARepository,
+		loginLockout:    repositories.NewMySQLLoginLockoutRepository,
+		loginHistory:    repositories.NewMySQLLoginHistoryRepository,
+		oauth:           repositories.NewMySQLOAuthRepository,
+	}
+}
+
 func fatalErr(err error) {
 	if err != nil {
 		log.Fatal(err)
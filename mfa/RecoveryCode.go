@@ -0,0 +1,49 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+const (
+	RecoveryCodeCount = 10
+	recoveryCodeSize  = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes returns count random codes formatted as
+// "xxxx-xxxx-xxxx-xxxx".
+func NewRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		raw := make([]byte, recoveryCodeSize)
+
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+
+		groups := make([]string, 0, len(encoded)/4)
+		for j := 0; j < len(encoded); j += 4 {
+			groups = append(groups, encoded[j:j+4])
+		}
+
+		codes = append(codes, strings.Join(groups, "-"))
+	}
+
+	return codes, nil
+}
+
+// NormalizeRecoveryCode lets users type codes without dashes or in any case.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+
+	return code
+}
@@ -0,0 +1,142 @@
+package mfa
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// rfcSeed is the SHA-1 seed of the RFC 4226 and RFC 6238 test vectors.
+var rfcSeed = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	testCases := []struct {
+		Counter uint64
+		Code    string
+	}{
+		{0, "755224"},
+		{1, "287082"},
+		{2, "359152"},
+		{5, "254676"},
+		{9, "520489"},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			code := hotp(sha1.New, rfcSeed, testCase.Counter, 6)
+			if code != testCase.Code {
+				t.Errorf("Code should be '%s', got '%s'", testCase.Code, code)
+			}
+		})
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	testCases := []struct {
+		Unix int64
+		Code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	secret := base32.StdEncoding.EncodeToString(rfcSeed)
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			step := TOTPStep(time.Unix(testCase.Unix, 0))
+
+			eightDigits := hotp(sha1.New, rfcSeed, uint64(step), 8)
+			if eightDigits != testCase.Code {
+				t.Errorf("Code should be '%s', got '%s'", testCase.Code, eightDigits)
+			}
+
+			code, err := TOTPCode(secret, step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != testCase.Code[2:] {
+				t.Errorf("Code should be '%s', got '%s'", testCase.Code[2:], code)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2023, 6, 1, 12, 0, 10, 0, time.UTC)
+	step := TOTPStep(now)
+
+	testCases := []struct {
+		Step     int64
+		Accepted bool
+	}{
+		{step, true},
+		{step - 1, true},
+		{step + 1, true},
+		{step - 2, false},
+		{step + 2, false},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			code, err := TOTPCode(secret, testCase.Step)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			matched, ok, err := ValidateTOTP(secret, code, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != testCase.Accepted {
+				t.Fatalf("Code of step %d should be accepted: %t", testCase.Step-step, testCase.Accepted)
+			}
+			if ok && matched != testCase.Step {
+				t.Errorf("Matched step should be '%d', got '%d'", testCase.Step, matched)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("simple-api", "diego@mail.com", "JBSWY3DPEHPK3PXP")
+	expected := "otpauth://totp/simple-api:diego@mail.com?algorithm=SHA1&digits=6&issuer=simple-api&period=30&secret=JBSWY3DPEHPK3PXP"
+
+	if uri != expected {
+		t.Errorf("URI should be '%s', got '%s'", expected, uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("Should make %d codes, got %d", RecoveryCodeCount, len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 19 || seen[code] {
+			t.Errorf("Codes should be unique and formatted as 'xxxx-xxxx-xxxx-xxxx', got '%s'", code)
+		}
+		seen[code] = true
+
+		if len(NormalizeRecoveryCode(code)) != 16 {
+			t.Errorf("Normalized code should have 16 characters, got '%s'", NormalizeRecoveryCode(code))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AuthUserIdKey is the context key holding the authenticated user id.
+	AuthUserIdKey = "authUserId"
+	// AuthUserEmailKey is the context key holding the authenticated user email.
+	AuthUserEmailKey = "authUserEmail"
+	// AuthClaimsKey is the context key holding the *dtos.TokenClaims of the
+	// bearer token.
+	AuthClaimsKey = "authClaims"
+
+	authRealm = "simple-api"
+)
+
+// Authenticate requires a valid "Authorization: Bearer <token>" header,
+// storing the token owner at AuthUserIdKey and AuthUserEmailKey and the
+// whole token claims at AuthClaimsKey.
+//
+// Responds with 401 and a WWW-Authenticate challenge otherwise, including for
+// tokens still pending a second authentication factor.
+func Authenticate(authenticator interfaces.Authenticator) func(*gin.Context) {
+	return func(ctx *gin.Context) {
+		token, found := bearerToken(ctx.GetHeader("Authorization"))
+		if !found {
+			unauthorized(ctx, fmt.Sprintf(`Bearer realm="%s"`, authRealm), "Missing bearer token")
+			return
+		}
+
+		claims, err := authenticator.ParseToken(token)
+		if err != nil {
+			if !utils.IsTokenError(err) {
+				utils.ErrorAbortResponse(ctx, utils.NewErrorCode(http.StatusInternalServerError, err))
+				return
+			}
+
+			unauthorized(
+				ctx,
+				fmt.Sprintf(`Bearer realm="%s", error="invalid_token"`, authRealm),
+				invalidTokenMessage(err),
+			)
+			return
+		}
+
+		if claims.HasScope(models.ScopeMFAPending) {
+			unauthorized(
+				ctx,
+				fmt.Sprintf(`Bearer realm="%s", error="invalid_token"`, authRealm),
+				"Bearer token is pending a second authentication factor",
+			)
+			return
+		}
+
+		ctx.Set(AuthUserIdKey, claims.UserID)
+		ctx.Set(AuthUserEmailKey, claims.Email)
+		ctx.Set(AuthClaimsKey, claims)
+
+		ctx.Next()
+	}
+}
+
+// AuthClaims returns the claims stored by Authenticate.
+func AuthClaims(ctx *gin.Context) *dtos.TokenClaims {
+	claims, _ := ctx.MustGet(AuthClaimsKey).(*dtos.TokenClaims)
+
+	return claims
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
+func invalidTokenMessage(err error) string {
+	switch {
+	case errors.Is(err, utils.ErrTokenExpired):
+		return "Bearer token expired"
+	case errors.Is(err, utils.ErrTokenRevoked):
+		return "Bearer token was revoked"
+	default:
+		return "Invalid bearer token"
+	}
+}
+
+func unauthorized(ctx *gin.Context, challenge, errMsg string) {
+	ctx.Header("WWW-Authenticate", challenge)
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, dtos.NewErrorMessageString(errMsg))
+}
@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestAuthenticate(t *testing.T) {
+	testCases := []struct {
+		header    string
+		respCode  int
+		respBody  string
+		challenge string
+	}{
+		{"Bearer valid-for(42)[diego@mail.com]", http.StatusOK, "42 diego@mail.com", ""},
+		{"bearer valid-for(7)[alex@mail.com]", http.StatusOK, "7 alex@mail.com", ""},
+		{"Bearer  valid-for(0)[r2d2@mail.com] ", http.StatusOK, "0 r2d2@mail.com", ""},
+		{"", http.StatusUnauthorized, "{\"error\":\"Missing bearer token\"}", "Bearer realm=\"simple-api\""},
+		{"Bearer", http.StatusUnauthorized, "{\"error\":\"Missing bearer token\"}", "Bearer realm=\"simple-api\""},
+		{"Bearer ", http.StatusUnauthorized, "{\"error\":\"Missing bearer token\"}", "Bearer realm=\"simple-api\""},
+		{"Basic dXNlcjpwYXNz", http.StatusUnauthorized, "{\"error\":\"Missing bearer token\"}", "Bearer realm=\"simple-api\""},
+		{"Bearer invalid-for(42)[diego@mail.com]", http.StatusUnauthorized, "{\"error\":\"Invalid bearer token\"}", "Bearer realm=\"simple-api\", error=\"invalid_token\""},
+		{"Bearer expired-for(42)[diego@mail.com]", http.StatusUnauthorized, "{\"error\":\"Bearer token expired\"}", "Bearer realm=\"simple-api\", error=\"invalid_token\""},
+		{"Bearer valid-for(13)[revoked@mail.com]", http.StatusUnauthorized, "{\"error\":\"Bearer token was revoked\"}", "Bearer realm=\"simple-api\", error=\"invalid_token\""},
+		{"Bearer injected-token", http.StatusOK, "1337 injected@mail.com", ""},
+		{"Bearer valid-for(x)[diego@mail.com]", http.StatusUnauthorized, "{\"error\":\"Invalid bearer token\"}", "Bearer realm=\"simple-api\", error=\"invalid_token\""},
+		{"Bearer mfa-pending-token", http.StatusUnauthorized, "{\"error\":\"Bearer token is pending a second authentication factor\"}", "Bearer realm=\"simple-api\", error=\"invalid_token\""},
+	}
+
+	authenticator := mocks.NewMockedAuthenticator()
+	authenticator.RevokeUserTokens(13)
+	authenticator.InjectIdentity("injected-token", &dtos.TokenClaims{UserID: 1337, Email: "injected@mail.com"})
+	authenticator.InjectIdentity("mfa-pending-token", &dtos.TokenClaims{UserID: 1337, Scopes: []string{models.ScopeMFAPending}})
+
+	engine := gin.New()
+
+	engine.GET("/", Authenticate(authenticator), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, fmt.Sprint(ctx.GetInt(AuthUserIdKey), " ", AuthClaims(ctx).Email))
+	})
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/", nil)
+			if _case.header != "" {
+				req.Header.Set("Authorization", _case.header)
+			}
+
+			engine.ServeHTTP(rec, req)
+			body := rec.Body.String()
+			challenge := rec.Header().Get("WWW-Authenticate")
+
+			if rec.Code != _case.respCode {
+				t.Errorf("Code should be '%d', got '%d'", _case.respCode, rec.Code)
+			}
+			if body != _case.respBody {
+				t.Errorf("Returned body should be '%s', got '%s'", _case.respBody, body)
+			}
+			if challenge != _case.challenge {
+				t.Errorf("WWW-Authenticate should be '%s', got '%s'", _case.challenge, challenge)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import "github.com/gin-contrib/cors"
 var CORS = cors.New(
 	cors.Config{
 		AllowAllOrigins: true, // Development
-		AllowMethods:    []string{"GET", "POST", "PATCH", "DELETE"},
+		AllowMethods:    []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:    []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		ExposeHeaders:   []string{"Location", "WWW-Authenticate"},
 	},
 )
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission only lets through callers whose roles grant permission,
+// and whose API key or OAuth token, if any, is scoped to it or not scoped at
+// all.
+//
+// Must run after Authenticate.
+func RequirePermission(permission string) func(*gin.Context) {
+	return func(ctx *gin.Context) {
+		claims := AuthClaims(ctx)
+
+		if authorization.HasPermission(claims.Roles, permission) && scopesAllow(claims, permission) {
+			ctx.Next()
+			return
+		}
+
+		ctx.AbortWithStatusJSON(
+			http.StatusForbidden,
+			dtos.NewErrorMessageString(fmt.Sprintf("Missing permission '%s'", permission)),
+		)
+	}
+}
+
+func scopesAllow(claims *dtos.TokenClaims, permission string) bool {
+	if !claims.IsDelegated() || len(claims.Scopes) == 0 {
+		return true
+	}
+
+	return claims.HasScope(permission)
+}
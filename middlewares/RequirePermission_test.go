@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRequirePermission(t *testing.T) {
+	testCases := []struct {
+		token    string
+		respCode int
+		respBody string
+	}{
+		{"admin-token", http.StatusOK, "deleted"},
+		{"user-token", http.StatusOK, "deleted"},
+		{"readonly-token", http.StatusForbidden, "{\"error\":\"Missing permission 'users:delete'\"}"},
+		{"valid-for(7)[noroles@mail.com]", http.StatusForbidden, "{\"error\":\"Missing permission 'users:delete'\"}"},
+		{"unknown-token", http.StatusUnauthorized, "{\"error\":\"Invalid bearer token\"}"},
+		{"unscoped-api-key", http.StatusOK, "deleted"},
+		{"scoped-api-key", http.StatusOK, "deleted"},
+		{"read-scoped-api-key", http.StatusForbidden, "{\"error\":\"Missing permission 'users:delete'\"}"},
+		{"readonly-api-key", http.StatusForbidden, "{\"error\":\"Missing permission 'users:delete'\"}"},
+		{"scoped-oauth-token", http.StatusOK, "deleted"},
+		{"read-scoped-oauth-token", http.StatusForbidden, "{\"error\":\"Missing permission 'users:delete'\"}"},
+		{"client-credentials-token", http.StatusForbidden, "{\"error\":\"Missing permission 'users:delete'\"}"},
+	}
+
+	authenticator := mocks.NewMockedAuthenticator()
+	authenticator.InjectIdentity("admin-token", &dtos.TokenClaims{UserID: 1, Roles: []string{models.RoleAdmin}})
+	authenticator.InjectIdentity("user-token", &dtos.TokenClaims{UserID: 2, Roles: []string{models.RoleUser}})
+	authenticator.InjectIdentity("readonly-token", &dtos.TokenClaims{UserID: 3, Roles: []string{models.RoleReadOnly}})
+	authenticator.InjectIdentity("unscoped-api-key", &dtos.TokenClaims{UserID: 2, Roles: []string{models.RoleUser}, APIKeyID: 1})
+	authenticator.InjectIdentity("scoped-api-key", &dtos.TokenClaims{UserID: 2, Roles: []string{models.RoleUser}, Scopes: []string{authorization.PermUsersDelete}, APIKeyID: 2})
+	authenticator.InjectIdentity("read-scoped-api-key", &dtos.TokenClaims{UserID: 2, Roles: []string{models.RoleUser}, Scopes: []string{authorization.PermUsersRead}, APIKeyID: 3})
+	authenticator.InjectIdentity("readonly-api-key", &dtos.TokenClaims{UserID: 3, Roles: []string{models.RoleReadOnly}, Scopes: []string{authorization.PermUsersDelete}, APIKeyID: 4})
+	authenticator.InjectIdentity("scoped-oauth-token", &dtos.TokenClaims{UserID: 2, Roles: []string{models.RoleUser}, Scopes: []string{authorization.PermUsersDelete}, ClientID: "app"})
+	authenticator.InjectIdentity("read-scoped-oauth-token", &dtos.TokenClaims{UserID: 2, Roles: []string{models.RoleUser}, Scopes: []string{authorization.PermUsersRead}, ClientID: "app"})
+	authenticator.InjectIdentity("client-credentials-token", &dtos.TokenClaims{Scopes: []string{authorization.PermUsersDelete}, ClientID: "service"})
+
+	engine := gin.New()
+
+	engine.DELETE("/", Authenticate(authenticator), RequirePermission(authorization.PermUsersDelete), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "deleted")
+	})
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+_case.token)
+
+			engine.ServeHTTP(rec, req)
+			body := rec.Body.String()
+
+			if rec.Code != _case.respCode {
+				t.Errorf("Code should be '%d', got '%d'", _case.respCode, rec.Code)
+			}
+			if body != _case.respBody {
+				t.Errorf("Returned body should be '%s', got '%s'", _case.respBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package validate
+
+import (
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// QueryCursor verifies the "cursor" of the query, when there is one, setting
+// it as the "cursor" of the context.
+//
+// Cursors can't be mixed with offsets, as they already hold the position.
+func QueryCursor(signer interfaces.CursorSigner) func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		token, ok := ctx.GetQuery("cursor")
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		if _, ok := ctx.GetQuery("offset"); ok {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, dtos.NewErrorMessageString("Query can't have both cursor and offset"))
+			return
+		}
+
+		cursor, err := signer.Verify(token)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, dtos.NewErrorMessageString("The cursor in the query is invalid"))
+			return
+		}
+
+		ctx.Set("cursor", cursor)
+		ctx.Next()
+	}
+}
+
+// Cursor returns the cursor verified by QueryCursor, nil when the query had
+// none.
+func Cursor(ctx *gin.Context) *dtos.Cursor {
+	cursor, _ := ctx.Get("cursor")
+	verified, _ := cursor.(*dtos.Cursor)
+
+	return verified
+}
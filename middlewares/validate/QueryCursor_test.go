@@ -0,0 +1,68 @@
+package validate
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+func TestQueryCursor(t *testing.T) {
+	signer, err := pagination.NewHMACCursorSigner(&config.Pagination{
+		Base64CursorKey: "Y3Vyc29ycy1vZi10aGUtdXNlcnMtbGlzdC1zaWduZXI",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _ := signer.Sign(&dtos.Cursor{Sort: "username", Key: "diego", ID: 7})
+
+	testCases := []struct {
+		query    string
+		respCode int
+		respBody string
+	}{
+		{"", http.StatusOK, "none"},
+		{"limit=5", http.StatusOK, "none"},
+		{"cursor=" + token, http.StatusOK, "username diego 7"},
+		{"cursor=" + token + "&limit=5", http.StatusOK, "username diego 7"},
+		{"cursor=" + token + "&offset=5", http.StatusBadRequest, "{\"error\":\"Query can't have both cursor and offset\"}"},
+		{"cursor=" + token[1:], http.StatusBadRequest, "{\"error\":\"The cursor in the query is invalid\"}"},
+		{"cursor=", http.StatusBadRequest, "{\"error\":\"The cursor in the query is invalid\"}"},
+		{"cursor=foo", http.StatusBadRequest, "{\"error\":\"The cursor in the query is invalid\"}"},
+	}
+
+	engine := gin.New()
+
+	engine.GET("/", QueryCursor(signer), func(ctx *gin.Context) {
+		cursor := Cursor(ctx)
+		if cursor == nil {
+			ctx.String(http.StatusOK, "none")
+			return
+		}
+
+		ctx.String(http.StatusOK, fmt.Sprintf("%s %s %d", cursor.Sort, cursor.Key, cursor.ID))
+	})
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/?"+_case.query, nil)
+
+			engine.ServeHTTP(rec, req)
+			body := rec.Body.String()
+
+			if rec.Code != _case.respCode {
+				t.Errorf("Code should be '%d', got '%d'", _case.respCode, rec.Code)
+			}
+			if body != _case.respBody {
+				t.Errorf("Returned body should be '%s', got '%s'", _case.respBody, body)
+			}
+		})
+	}
+}
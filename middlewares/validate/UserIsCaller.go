@@ -0,0 +1,28 @@
+package validate
+
+import (
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// UserIsCaller only lets the authenticated user act on their own user ID,
+// unless they are an administrator.
+//
+// Must run after middlewares.Authenticate and PathUserId.
+func UserIsCaller(ctx *gin.Context) {
+	claims := middlewares.AuthClaims(ctx)
+
+	if claims.UserID == ctx.GetInt("id") || claims.HasRole(models.RoleAdmin) {
+		ctx.Next()
+		return
+	}
+
+	ctx.AbortWithStatusJSON(
+		http.StatusForbidden,
+		dtos.NewErrorMessageString("Not allowed to act on behalf of another user"),
+	)
+}
@@ -0,0 +1,93 @@
+package validate
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestUserIsCaller(t *testing.T) {
+	testCases := []struct {
+		token    string
+		userId   string
+		respCode int
+		respBody string
+	}{
+		{"valid-for(0)[diego@mail.com]", "0", http.StatusOK, "0"},
+		{"valid-for(1)[alex@mail.com]", "1", http.StatusOK, "1"},
+		{"valid-for(0)[diego@mail.com]", "1", http.StatusForbidden, "{\"error\":\"Not allowed to act on behalf of another user\"}"},
+		{"valid-for(1)[alex@mail.com]", "0", http.StatusForbidden, "{\"error\":\"Not allowed to act on behalf of another user\"}"},
+		{"valid-for(1)[alex@mail.com]", "5", http.StatusForbidden, "{\"error\":\"Not allowed to act on behalf of another user\"}"},
+		{"valid-for(5)[ghost@mail.com]", "5", http.StatusNotFound, ""},
+		{"admin-token", "0", http.StatusOK, "0"},
+		{"admin-token", "1", http.StatusOK, "1"},
+		{"admin-token", "5", http.StatusNotFound, ""},
+		{"user-token", "0", http.StatusForbidden, "{\"error\":\"Not allowed to act on behalf of another user\"}"},
+	}
+
+	userRepo := mocks.NewMockedUserRepository()
+
+	userRepo.CreateUser(&dtos.UserWithHash{
+		UserModel: models.UserModel{UserName: "Diego", Email: "diego@mail.com"},
+		Hash:      "fb78ed1e-a121-542f-a68d-fcd21ffe83c5",
+	})
+	userRepo.CreateUser(&dtos.UserWithHash{
+		UserModel: models.UserModel{UserName: "Alex", Email: "alex@mail.com"},
+		Hash:      "d296ee89-edba-58c6-8745-d45557cefb90",
+	})
+
+	authenticator := mocks.NewMockedAuthenticator()
+	authenticator.InjectIdentity("admin-token", &dtos.TokenClaims{
+		UserID: 42,
+		Email:  "admin@mail.com",
+		Roles:  []string{models.RoleAdmin},
+	})
+	authenticator.InjectIdentity("user-token", &dtos.TokenClaims{
+		UserID: 43,
+		Email:  "user@mail.com",
+		Roles:  []string{"user"},
+	})
+
+	engine := gin.New()
+
+	engine.GET(
+		"/:id",
+		middlewares.Authenticate(authenticator),
+		PathUserId,
+		UserIsCaller,
+		UserIdExist(userRepo),
+		func(ctx *gin.Context) {
+			id := ctx.GetInt("id")
+			ctx.String(http.StatusOK, fmt.Sprint(id))
+		},
+	)
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/"+_case.userId, nil)
+			req.Header.Set("Authorization", "Bearer "+_case.token)
+
+			engine.ServeHTTP(rec, req)
+			body := rec.Body.String()
+
+			if rec.Code != _case.respCode {
+				t.Errorf("Code should be '%d', got '%d'", _case.respCode, rec.Code)
+			}
+			if body != _case.respBody {
+				t.Errorf("Returned body should be '%s', got '%s'", _case.respBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// upgradeMySQLBaseline brings the tables adopted by the baseline migrations,
+// as the repositories created them before migrations existed, up to the
+// columns and indexes the baseline would have created.
+//
+// Each step checks the schema first, so databases created by the migrations
+// are left as they are.
+func upgradeMySQLBaseline(ctx context.Context, conn *sql.Conn) error {
+	// Users created before email verification existed are trusted as verified
+	added, err := addColumnIfNotExist(ctx, conn, "users", "verified_at", "DATETIME NULL")
+	if err != nil {
+		return err
+	}
+
+	if added {
+		_, err = conn.ExecContext(ctx, `
+			UPDATE users
+			SET verified_at = UTC_TIMESTAMP();
+		`)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = addColumnIfNotExist(ctx, conn, "users", "last_login_at", "DATETIME NULL")
+	if err != nil {
+		return err
+	}
+
+	// Tables created before PHC hashes only fit bcrypt ones
+	var dataType string
+	var length sql.NullInt64
+
+	err = conn.QueryRowContext(ctx, `
+		SELECT
+			data_type,
+			character_maximum_length
+		FROM
+			information_schema.columns
+		WHERE
+			table_schema = DATABASE() AND
+			table_name = 'users' AND
+			column_name = 'hash';
+	`).Scan(&dataType, &length)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(dataType, "varchar") || length.Int64 < 255 {
+		_, err = conn.ExecContext(ctx, `
+			ALTER TABLE users
+			MODIFY hash VARCHAR(255) NOT NULL;
+		`)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = addIndexIfNotExist(ctx, conn, "users", "users_search", "FULLTEXT", "username, email")
+	if err != nil {
+		return err
+	}
+
+	_, err = addColumnIfNotExist(ctx, conn, "user_tokens", "payload", "VARCHAR(255) NOT NULL DEFAULT '' AFTER hash")
+	if err != nil {
+		return err
+	}
+
+	_, err = addColumnIfNotExist(ctx, conn, "oauth_authorization_codes", "nonce", "VARCHAR(512) NOT NULL DEFAULT ''")
+
+	return err
+}
+
+// addColumnIfNotExist adds the column to tables created before it existed,
+// returning whether it was added.
+func addColumnIfNotExist(ctx context.Context, conn *sql.Conn, table string, column string, definition string) (bool, error) {
+	row := conn.QueryRowContext(ctx, `
+		SELECT
+			count(*)
+		FROM
+			information_schema.columns
+		WHERE
+			table_schema = DATABASE() AND
+			table_name = ? AND
+			column_name = ?;
+	`, table, column)
+
+	var columnCount int
+
+	err := row.Scan(&columnCount)
+	if err != nil {
+		return false, err
+	}
+
+	if columnCount > 0 {
+		return false, nil
+	}
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, definition))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// addIndexIfNotExist adds the index of columns to tables created before it
+// existed. Kind is the kind of index, such as "UNIQUE" or "FULLTEXT".
+func addIndexIfNotExist(ctx context.Context, conn *sql.Conn, table string, index string, kind string, columns string) error {
+	row := conn.QueryRowContext(ctx, `
+		SELECT
+			count(*)
+		FROM
+			information_schema.statistics
+		WHERE
+			table_schema = DATABASE() AND
+			table_name = ? AND
+			index_name = ?;
+	`, table, index)
+
+	var indexCount int
+
+	err := row.Scan(&indexCount)
+	if err != nil {
+		return err
+	}
+
+	if indexCount > 0 {
+		return nil
+	}
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("CREATE %s INDEX %s ON %s (%s);", kind, index, table, columns))
+
+	return err
+}
@@ -0,0 +1,224 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/config"
+)
+
+// fakeSchema answers the information_schema queries of upgradeMySQLBaseline
+// from the columns and indexes of its tables, applying its schema changes.
+type fakeSchema struct {
+	// columns holds the type of each column of each table, as "char(72)"
+	columns  map[string]map[string]string
+	indexes  map[string]bool
+	executed []string
+}
+
+func (s *fakeSchema) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{schema: s}, nil
+}
+
+func (s *fakeSchema) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	schema *fakeSchema
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{schema: c.schema, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions aren't faked")
+}
+
+type fakeStmt struct {
+	schema *fakeSchema
+	query  string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.schema.executed = append(s.schema.executed, s.query)
+
+	fields := strings.Fields(strings.TrimSuffix(s.query, ";"))
+
+	switch {
+	case strings.Contains(s.query, "ADD COLUMN"):
+		// ALTER TABLE table ADD COLUMN column type ...
+		s.schema.columns[fields[2]][fields[5]] = strings.ToLower(fields[6])
+	case strings.Contains(s.query, "MODIFY"):
+		// ALTER TABLE table MODIFY column type ...
+		s.schema.columns[fields[2]][fields[4]] = strings.ToLower(fields[5])
+	case strings.HasPrefix(s.query, "CREATE"):
+		// CREATE kind INDEX index ON table ...
+		s.schema.indexes[fields[5]+"."+fields[3]] = true
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	switch {
+	case strings.Contains(s.query, "data_type"):
+		dataType, length, _ := strings.Cut(s.schema.columns["users"]["hash"], "(")
+		maxLength, _ := strconv.Atoi(strings.TrimSuffix(length, ")"))
+		return &fakeRows{columns: []string{"data_type", "character_maximum_length"}, values: []driver.Value{dataType, int64(maxLength)}}, nil
+	case strings.Contains(s.query, "information_schema.columns"):
+		_, ok := s.schema.columns[args[0].(string)][args[1].(string)]
+		return countRows(ok), nil
+	case strings.Contains(s.query, "information_schema.statistics"):
+		return countRows(s.schema.indexes[args[0].(string)+"."+args[1].(string)]), nil
+	}
+
+	return nil, fmt.Errorf("query '%s' isn't faked", s.query)
+}
+
+func countRows(exists bool) *fakeRows {
+	count := int64(0)
+	if exists {
+		count = 1
+	}
+
+	return &fakeRows{columns: []string{"count(*)"}, values: []driver.Value{count}}
+}
+
+// fakeRows is a single row of values.
+type fakeRows struct {
+	columns []string
+	values  []driver.Value
+	read    bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+
+	copy(dest, r.values)
+
+	return nil
+}
+
+// baselineSchema is the schema the repositories created before migrations
+// existed, once the baseline migrations adopted it.
+func baselineSchema() *fakeSchema {
+	return &fakeSchema{
+		columns: map[string]map[string]string{
+			"users":                     {"id": "int", "username": "varchar(50)", "email": "varchar(100)", "hash": "char(72)"},
+			"user_tokens":               {"id": "int", "user_id": "int", "purpose": "varchar(30)", "hash": "char(64)"},
+			"oauth_authorization_codes": {"id": "int", "hash": "char(64)", "client_id": "varchar(32)"},
+		},
+		indexes: map[string]bool{},
+	}
+}
+
+func upgrade(t *testing.T, schema *fakeSchema) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db := sql.OpenDB(schema)
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	schema.executed = nil
+
+	err = upgradeMySQLBaseline(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpgradeMySQLBaseline(t *testing.T) {
+	schema := baselineSchema()
+
+	upgrade(t, schema)
+
+	testCases := []struct {
+		table  string
+		column string
+		want   string
+	}{
+		{"users", "verified_at", "datetime"},
+		{"users", "last_login_at", "datetime"},
+		{"users", "hash", "varchar(255)"},
+		{"user_tokens", "payload", "varchar(255)"},
+		{"oauth_authorization_codes", "nonce", "varchar(512)"},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			if got := schema.columns[_case.table][_case.column]; got != _case.want {
+				t.Errorf("Column %s.%s should be '%s', got '%s'", _case.table, _case.column, _case.want, got)
+			}
+		})
+	}
+
+	if !schema.indexes["users.users_search"] {
+		t.Error("Users should have the users_search index")
+	}
+
+	backfilled := false
+	for _, statement := range schema.executed {
+		backfilled = backfilled || strings.HasPrefix(statement, "UPDATE users SET verified_at")
+	}
+	if !backfilled {
+		t.Errorf("Existing users should be verified, got %q", schema.executed)
+	}
+
+	// Upgraded databases, as the ones created by the migrations, are kept
+	upgrade(t, schema)
+
+	if len(schema.executed) != 0 {
+		t.Errorf("Upgraded schema should be left as it is, got %q", schema.executed)
+	}
+}
+
+func TestLoadMigrations_Coded(t *testing.T) {
+	files, err := fs.Sub(migrationFiles, config.DriverMySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loadMigrations(files, &migration{version: 1, name: "create_users", upFunc: keepSchema})
+	if err == nil || err.Error() != "migration 0001_create_users is both in a file and coded" {
+		t.Errorf("Error should be 'migration 0001_create_users is both in a file and coded', got '%v'", err)
+	}
+}
@@ -0,0 +1,509 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/database"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+// SQLMigrator implements Migrator
+var _ interfaces.Migrator = (*SQLMigrator)(nil)
+
+// migrationFiles holds a directory of migrations per driver, named as
+// "0001_create_users.up.sql" and "0001_create_users.down.sql".
+//
+//go:embed mysql/*.sql postgres/*.sql
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// codedMigrations holds, per driver, the migrations depending on the state of
+// the schema, which SQL alone can't check on MySQL.
+var codedMigrations = map[string][]*migration{
+	config.DriverMySQL: {
+		{version: 11, name: "upgrade_baseline", upFunc: upgradeMySQLBaseline, downFunc: keepSchema},
+	},
+}
+
+const (
+	lockName           = "schema_migrations"
+	lockTimeoutSeconds = 60
+)
+
+var ErrLocked = errors.New("another instance is migrating the database")
+
+// migration is a step of the schema, applied by up and undone by down, or
+// by upFunc and downFunc when coded.
+type migration struct {
+	version  int
+	name     string
+	up       string
+	down     string
+	upFunc   func(ctx context.Context, conn *sql.Conn) error
+	downFunc func(ctx context.Context, conn *sql.Conn) error
+}
+
+func (m migration) apply(ctx context.Context, conn *sql.Conn) error {
+	if m.upFunc != nil {
+		return m.upFunc(ctx, conn)
+	}
+
+	return execStatements(ctx, conn, m.up)
+}
+
+func (m migration) undo(ctx context.Context, conn *sql.Conn) error {
+	if m.downFunc != nil {
+		return m.downFunc(ctx, conn)
+	}
+
+	return execStatements(ctx, conn, m.down)
+}
+
+func (m migration) undoable() bool {
+	return m.down != "" || m.downFunc != nil
+}
+
+// keepSchema undoes the migrations that only brought older tables up to the
+// schema of previous migrations, which undo it themselves.
+func keepSchema(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+// SQLMigrator applies the migrations embedded and coded for the driver of
+// the database in order, recording each in the schema_migrations table.
+//
+// Migrations aren't run inside transactions, MySQL committing schema changes
+// right away, so a migration failing halfway must be fixed by hand before
+// being retried.
+type SQLMigrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []*migration
+	now        func() time.Time
+}
+
+// NewSQLMigrator returns a migrator for the driver of database. RamMySQL
+// databases live in memory, with no schema to migrate, so there's nothing to
+// apply on them.
+func NewSQLMigrator(database interfaces.Database) (interfaces.Migrator, error) {
+	if database.Driver() == config.DriverRamSQL {
+		return &SQLMigrator{
+			db:         database.DB(),
+			driver:     database.Driver(),
+			migrations: []*migration{},
+			now:        time.Now,
+		}, nil
+	}
+
+	files, err := fs.Sub(migrationFiles, database.Driver())
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations(files, codedMigrations[database.Driver()]...)
+	if err != nil {
+		return nil, fmt.Errorf("migrations of the %s driver: %w", database.Driver(), err)
+	}
+
+	return &SQLMigrator{
+		db:         database.DB(),
+		driver:     database.Driver(),
+		migrations: migrations,
+		now:        time.Now,
+	}, nil
+}
+
+// Up applies every pending migration, returning them.
+func (m SQLMigrator) Up() ([]*dtos.Migration, error) {
+	applied := make([]*dtos.Migration, 0)
+	if len(m.migrations) == 0 {
+		return applied, nil
+	}
+
+	err := m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		appliedAt, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := appliedAt[migration.version]; ok {
+				continue
+			}
+
+			err := migration.apply(ctx, conn)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.version, migration.name, err)
+			}
+
+			now := m.now().UTC().Truncate(time.Second)
+
+			_, err = conn.ExecContext(ctx, m.bind(`
+				INSERT INTO schema_migrations
+					(version, name, applied_at)
+				VALUES
+					(?, ?, ?);
+			`), migration.version, migration.name, now)
+			if err != nil {
+				return err
+			}
+
+			applied = append(applied, &dtos.Migration{
+				Version:   migration.version,
+				Name:      migration.name,
+				AppliedAt: &now,
+			})
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down undoes the last steps applied migrations, newest first, returning
+// them.
+func (m SQLMigrator) Down(steps int) ([]*dtos.Migration, error) {
+	reverted := make([]*dtos.Migration, 0, steps)
+	if len(m.migrations) == 0 {
+		return reverted, nil
+	}
+
+	err := m.locked(func(ctx context.Context, conn *sql.Conn) error {
+		appliedAt, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(appliedAt))
+		for version := range appliedAt {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		for _, version := range versions {
+			migration := m.find(version)
+			if migration == nil {
+				return fmt.Errorf("migration %04d is unknown to this version of the API", version)
+			}
+			if !migration.undoable() {
+				return fmt.Errorf("migration %04d_%s can't be undone", migration.version, migration.name)
+			}
+
+			err := migration.undo(ctx, conn)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.version, migration.name, err)
+			}
+
+			_, err = conn.ExecContext(ctx, m.bind(`
+				DELETE FROM schema_migrations
+				WHERE version = ?;
+			`), version)
+			if err != nil {
+				return err
+			}
+
+			reverted = append(reverted, &dtos.Migration{
+				Version: migration.version,
+				Name:    migration.name,
+			})
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns every migration, telling when each was applied.
+func (m SQLMigrator) Status() ([]*dtos.Migration, error) {
+	if len(m.migrations) == 0 {
+		return []*dtos.Migration{}, nil
+	}
+
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	appliedAt, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]*dtos.Migration, len(m.migrations))
+
+	for i, migration := range m.migrations {
+		status[i] = &dtos.Migration{
+			Version: migration.version,
+			Name:    migration.name,
+		}
+
+		if at, ok := appliedAt[migration.version]; ok {
+			status[i].AppliedAt = &at
+		}
+	}
+
+	return status, nil
+}
+
+func (m SQLMigrator) find(version int) *migration {
+	for _, migration := range m.migrations {
+		if migration.version == version {
+			return migration
+		}
+	}
+
+	return nil
+}
+
+// locked runs migrate on a connection holding the migrations lock, so
+// instances starting together don't apply the same migrations twice.
+func (m SQLMigrator) locked(migrate func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	// Locks belong to the connection taking them, not to the whole pool
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch m.driver {
+	case config.DriverMySQL:
+		var acquired sql.NullInt64
+
+		err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?);`, lockName, lockTimeoutSeconds).Scan(&acquired)
+		if err != nil {
+			return err
+		}
+		if acquired.Int64 != 1 {
+			return ErrLocked
+		}
+		defer conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?);`, lockName)
+	case config.DriverPostgres:
+		err = postgresAdvisoryLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1));`, lockName)
+	default:
+		return fmt.Errorf("migrations can't be locked on the %s driver", m.driver)
+	}
+
+	return migrate(ctx, conn)
+}
+
+// postgresAdvisoryLock takes the migrations lock on conn, waiting for it as
+// long as MySQL does, since pg_advisory_lock would wait forever.
+func postgresAdvisoryLock(ctx context.Context, conn *sql.Conn) error {
+	deadline := time.Now().Add(lockTimeoutSeconds * time.Second)
+
+	for {
+		var acquired bool
+
+		err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1));`, lockName).Scan(&acquired)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrLocked
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// bind numbers the "?" placeholders of query when the driver wants them as
+// "$1", "$2" and so on.
+func (m SQLMigrator) bind(query string) string {
+	if m.driver == config.DriverPostgres {
+		return database.Rebind(query)
+	}
+
+	return query
+}
+
+// appliedVersions returns when each applied migration was, creating the
+// schema_migrations table on the first run.
+func (m SQLMigrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	timestamp := "DATETIME "
+	if m.driver == config.DriverPostgres {
+		timestamp = "TIMESTAMP"
+	}
+
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations(
+			version    INTEGER      NOT NULL PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			applied_at `+timestamp+`    NOT NULL
+		);
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT
+			version,
+			applied_at
+		FROM
+			schema_migrations;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+
+	for rows.Next() {
+		var version int
+		var at time.Time
+
+		err := rows.Scan(&version, &at)
+		if err != nil {
+			return nil, err
+		}
+
+		appliedAt[version] = at
+	}
+
+	return appliedAt, rows.Err()
+}
+
+// execStatements runs each statement of script, which are ended by a
+// semicolon at the end of a line.
+func execStatements(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, statement := range splitStatements(script) {
+		_, err := conn.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func splitStatements(script string) []string {
+	statements := make([]string, 0)
+	var statement strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(statement.String()))
+			statement.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(statement.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
+
+// loadMigrations reads the migrations of files, along with the coded ones,
+// ordered by version.
+//
+// Errors can be caused by:
+// files not being named as migrations;
+// two migrations having the same version;
+// a migration not having an up file.
+func loadMigrations(files fs.FS, coded ...*migration) ([]*migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("'%s' isn't named as a migration", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		name, direction := match[2], match[3]
+
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		step, ok := byVersion[version]
+		if !ok {
+			step = &migration{version: version, name: name}
+			byVersion[version] = step
+		}
+		if step.name != name {
+			return nil, fmt.Errorf("migration %04d is named both '%s' and '%s'", version, step.name, name)
+		}
+
+		if direction == "up" {
+			step.up = string(content)
+		} else {
+			step.down = string(content)
+		}
+	}
+
+	for _, step := range coded {
+		if _, ok := byVersion[step.version]; ok {
+			return nil, fmt.Errorf("migration %04d_%s is both in a file and coded", step.version, step.name)
+		}
+
+		byVersion[step.version] = step
+	}
+
+	if len(byVersion) == 0 {
+		return nil, errors.New("no migrations found")
+	}
+
+	migrations := make([]*migration, 0, len(byVersion))
+
+	for _, step := range byVersion {
+		if step.up == "" && step.upFunc == nil {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", step.version, step.name)
+		}
+
+		migrations = append(migrations, step)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
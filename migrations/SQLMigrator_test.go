@@ -0,0 +1,138 @@
+package migrations
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/database"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	for _, driver := range []string{config.DriverMySQL, config.DriverPostgres} {
+		t.Run(driver, func(t *testing.T) {
+			files, err := fs.Sub(migrationFiles, driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			migrations, err := loadMigrations(files, codedMigrations[driver]...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, migration := range migrations {
+				if migration.version != i+1 {
+					t.Errorf("Migration %d should have version '%d', got '%d'", i, i+1, migration.version)
+				}
+				if !migration.undoable() {
+					t.Errorf("Migration %04d_%s should be undoable", migration.version, migration.name)
+				}
+			}
+
+			if migrations[0].name != "create_users" {
+				t.Errorf("First migration should create the users, got '%s'", migrations[0].name)
+			}
+		})
+	}
+}
+
+func TestSQLMigrator_RamSQL(t *testing.T) {
+	ramMySQL, err := database.NewRamMySQL(&config.Database{Driver: config.DriverRamSQL, DBName: "migrations"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ramMySQL.Close()
+
+	migrator, err := NewSQLMigrator(ramMySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil || len(applied) != 0 {
+		t.Errorf("RamSQL should have nothing to migrate, got %v (%v)", applied, err)
+	}
+
+	status, err := migrator.Status()
+	if err != nil || len(status) != 0 {
+		t.Errorf("RamSQL should have no migration, got %v (%v)", status, err)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	testCases := []struct {
+		files    fstest.MapFS
+		versions string
+		err      string
+	}{
+		{fstest.MapFS{
+			"0002_b.up.sql":   file("CREATE TABLE b(id INTEGER);"),
+			"0001_a.up.sql":   file("CREATE TABLE a(id INTEGER);"),
+			"0001_a.down.sql": file("DROP TABLE a;"),
+			"0010_c.up.sql":   file("CREATE TABLE c(id INTEGER);"),
+		}, "[1 2 10]", ""},
+		{fstest.MapFS{}, "", "no migrations found"},
+		{fstest.MapFS{"README.md": file("")}, "", "'README.md' isn't named as a migration"},
+		{fstest.MapFS{"0001_a.sql": file("")}, "", "'0001_a.sql' isn't named as a migration"},
+		{fstest.MapFS{"0001_a.down.sql": file("DROP TABLE a;")}, "", "migration 0001_a has no up file"},
+		{fstest.MapFS{
+			"0001_a.up.sql": file("CREATE TABLE a(id INTEGER);"),
+			"0001_b.up.sql": file("CREATE TABLE b(id INTEGER);"),
+		}, "", "migration 0001 is named both 'a' and 'b'"},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			migrations, err := loadMigrations(_case.files)
+			if _case.err != "" {
+				if err == nil || err.Error() != _case.err {
+					t.Errorf("Error should be '%s', got '%v'", _case.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			versions := make([]int, len(migrations))
+			for j, migration := range migrations {
+				versions[j] = migration.version
+			}
+
+			if fmt.Sprint(versions) != _case.versions {
+				t.Errorf("Versions should be '%s', got '%v'", _case.versions, versions)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		script     string
+		statements []string
+	}{
+		{"DROP TABLE a;", []string{"DROP TABLE a;"}},
+		{"DROP TABLE a;\n\nDROP TABLE b;\n", []string{"DROP TABLE a;", "DROP TABLE b;"}},
+		{"-- Users first\nCREATE TABLE a(\n\tid INTEGER,\n\tname VARCHAR(10) DEFAULT ';'\n);", []string{"CREATE TABLE a(\n\tid INTEGER,\n\tname VARCHAR(10) DEFAULT ';'\n);"}},
+		{"UPDATE a SET b = 1", []string{"UPDATE a SET b = 1"}},
+		{"\n-- Nothing\n\n", []string{}},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			statements := splitStatements(_case.script)
+
+			if strings.Join(statements, "|") != strings.Join(_case.statements, "|") || len(statements) != len(_case.statements) {
+				t.Errorf("Statements should be %q, got %q", _case.statements, statements)
+			}
+		})
+	}
+}
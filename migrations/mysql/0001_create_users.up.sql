@@ -0,0 +1,20 @@
+-- Baseline migrations use IF NOT EXISTS to adopt the databases whose tables
+-- the repositories created themselves, before migrations existed. The columns
+-- and indexes those tables miss are added by 0011_upgrade_baseline
+
+CREATE TABLE IF NOT EXISTS users(
+	id            INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	username      VARCHAR(50)  NOT NULL,
+	email         VARCHAR(100) NOT NULL UNIQUE,
+	hash          VARCHAR(255) NOT NULL,
+	verified_at   DATETIME     NULL,
+	last_login_at DATETIME     NULL,
+	FULLTEXT INDEX users_search (username, email)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles(
+	user_id INTEGER     NOT NULL,
+	role    VARCHAR(20) NOT NULL,
+	PRIMARY KEY (user_id, role),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_tokens(
+	id         INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id    INTEGER      NOT NULL,
+	purpose    VARCHAR(30)  NOT NULL,
+	hash       CHAR(64)     NOT NULL UNIQUE,
+	payload    VARCHAR(255) NOT NULL DEFAULT '',
+	expires_at DATETIME     NOT NULL,
+	used_at    DATETIME     NULL,
+	INDEX (user_id, purpose),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
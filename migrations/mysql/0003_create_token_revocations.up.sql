@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens(
+	token_id   CHAR(22) NOT NULL PRIMARY KEY,
+	expires_at DATETIME NOT NULL,
+	INDEX (expires_at)
+);
+
+CREATE TABLE IF NOT EXISTS user_token_revocations(
+	user_id       INTEGER  NOT NULL PRIMARY KEY,
+	issued_before DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens(
+	id         INTEGER  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id    INTEGER  NOT NULL,
+	family_id  CHAR(22) NOT NULL,
+	hash       CHAR(64) NOT NULL UNIQUE,
+	expires_at DATETIME NOT NULL,
+	used_at    DATETIME NULL,
+	revoked_at DATETIME NULL,
+	INDEX (family_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
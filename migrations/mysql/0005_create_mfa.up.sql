@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS user_totp(
+	user_id        INTEGER     NOT NULL PRIMARY KEY,
+	secret         VARCHAR(64) NOT NULL,
+	confirmed_at   DATETIME    NULL,
+	last_used_step BIGINT      NOT NULL DEFAULT 0,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes(
+	id      INTEGER  NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id INTEGER  NOT NULL,
+	hash    CHAR(64) NOT NULL,
+	used_at DATETIME NULL,
+	UNIQUE (user_id, hash),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
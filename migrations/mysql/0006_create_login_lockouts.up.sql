@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS login_lockouts(
+	kind            VARCHAR(10)  NOT NULL,
+	subject         VARCHAR(100) NOT NULL,
+	failures        INTEGER      NOT NULL,
+	last_failure_at DATETIME     NOT NULL,
+	locked_until    DATETIME     NULL,
+	PRIMARY KEY (kind, subject),
+	INDEX (locked_until)
+);
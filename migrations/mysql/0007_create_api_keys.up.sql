@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys(
+	id         INTEGER       NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id    INTEGER       NOT NULL,
+	name       VARCHAR(100)  NOT NULL,
+	prefix     VARCHAR(16)   NOT NULL,
+	hash       CHAR(64)      NOT NULL UNIQUE,
+	scopes     VARCHAR(1024) NOT NULL DEFAULT '',
+	created_at DATETIME      NOT NULL,
+	expires_at DATETIME      NULL,
+	revoked_at DATETIME      NULL,
+	INDEX (user_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
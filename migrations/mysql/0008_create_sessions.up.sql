@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS sessions(
+	id           CHAR(22)     NOT NULL PRIMARY KEY,
+	user_id      INTEGER      NOT NULL,
+	user_agent   VARCHAR(255) NOT NULL DEFAULT '',
+	ip           VARCHAR(45)  NOT NULL DEFAULT '',
+	created_at   DATETIME     NOT NULL,
+	last_seen_at DATETIME     NOT NULL,
+	expires_at   DATETIME     NOT NULL,
+	revoked_at   DATETIME     NULL,
+	INDEX (user_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
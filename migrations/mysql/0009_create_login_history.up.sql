@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS login_history(
+	id         INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
+	user_id    INTEGER      NULL,
+	email      VARCHAR(100) NOT NULL,
+	ip         VARCHAR(45)  NOT NULL DEFAULT '',
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	succeeded  BOOLEAN      NOT NULL,
+	reason     VARCHAR(30)  NOT NULL,
+	created_at DATETIME     NOT NULL,
+	INDEX (user_id, created_at),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- Nothing to undo, the backfilled roles can't be told apart from the granted
+-- ones
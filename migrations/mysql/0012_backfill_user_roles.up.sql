@@ -0,0 +1,17 @@
+-- Users created before roles existed get the role every new user gets
+
+INSERT INTO user_roles(user_id, role)
+SELECT
+	id,
+	'user'
+FROM
+	users
+WHERE
+	NOT EXISTS (
+		SELECT
+			1
+		FROM
+			user_roles
+		WHERE
+			user_roles.user_id = users.id
+	);
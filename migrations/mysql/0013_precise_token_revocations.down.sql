@@ -0,0 +1,2 @@
+ALTER TABLE user_token_revocations
+	MODIFY issued_before DATETIME NOT NULL;
@@ -0,0 +1,5 @@
+-- Revocations of every token of a user keep the microseconds tokens are
+-- issued at, so the tokens issued later in the same second stay valid
+
+ALTER TABLE user_token_revocations
+	MODIFY issued_before DATETIME(6) NOT NULL;
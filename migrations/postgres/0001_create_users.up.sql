@@ -0,0 +1,23 @@
+CREATE TABLE users(
+	id            SERIAL       NOT NULL PRIMARY KEY,
+	username      VARCHAR(50)  NOT NULL,
+	email         VARCHAR(100) NOT NULL,
+	hash          VARCHAR(255) NOT NULL,
+	verified_at   TIMESTAMPTZ  NULL,
+	last_login_at TIMESTAMPTZ  NULL
+);
+
+-- Emails are unique whatever their case, as in the MySQL collation
+CREATE UNIQUE INDEX users_email ON users (lower(email));
+
+-- Same expression as the one searched by PostgresUserRepository.SearchUsers
+CREATE INDEX users_search ON users USING GIN (
+	to_tsvector('simple', regexp_replace(username || ' ' || email, '[^[:alnum:]]+', ' ', 'g'))
+);
+
+CREATE TABLE user_roles(
+	user_id INTEGER     NOT NULL,
+	role    VARCHAR(20) NOT NULL,
+	PRIMARY KEY (user_id, role),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
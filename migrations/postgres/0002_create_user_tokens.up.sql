@@ -0,0 +1,12 @@
+CREATE TABLE user_tokens(
+	id         SERIAL       NOT NULL PRIMARY KEY,
+	user_id    INTEGER      NOT NULL,
+	purpose    VARCHAR(30)  NOT NULL,
+	hash       CHAR(64)     NOT NULL UNIQUE,
+	payload    VARCHAR(255) NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ  NOT NULL,
+	used_at    TIMESTAMPTZ  NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX user_tokens_user ON user_tokens (user_id, purpose);
@@ -0,0 +1,12 @@
+CREATE TABLE revoked_tokens(
+	token_id   CHAR(22)    NOT NULL PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX revoked_tokens_expiry ON revoked_tokens (expires_at);
+
+CREATE TABLE user_token_revocations(
+	user_id       INTEGER     NOT NULL PRIMARY KEY,
+	issued_before TIMESTAMPTZ NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
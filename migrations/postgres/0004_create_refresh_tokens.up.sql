@@ -0,0 +1,12 @@
+CREATE TABLE refresh_tokens(
+	id         SERIAL      NOT NULL PRIMARY KEY,
+	user_id    INTEGER     NOT NULL,
+	family_id  CHAR(22)    NOT NULL,
+	hash       CHAR(64)    NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at    TIMESTAMPTZ NULL,
+	revoked_at TIMESTAMPTZ NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX refresh_tokens_family ON refresh_tokens (family_id);
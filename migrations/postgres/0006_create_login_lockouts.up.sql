@@ -0,0 +1,10 @@
+CREATE TABLE login_lockouts(
+	kind            VARCHAR(10)  NOT NULL,
+	subject         VARCHAR(100) NOT NULL,
+	failures        INTEGER      NOT NULL,
+	last_failure_at TIMESTAMPTZ  NOT NULL,
+	locked_until    TIMESTAMPTZ  NULL,
+	PRIMARY KEY (kind, subject)
+);
+
+CREATE INDEX login_lockouts_until ON login_lockouts (locked_until);
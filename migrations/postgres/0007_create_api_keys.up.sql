@@ -0,0 +1,14 @@
+CREATE TABLE api_keys(
+	id         SERIAL        NOT NULL PRIMARY KEY,
+	user_id    INTEGER       NOT NULL,
+	name       VARCHAR(100)  NOT NULL,
+	prefix     VARCHAR(16)   NOT NULL,
+	hash       CHAR(64)      NOT NULL UNIQUE,
+	scopes     VARCHAR(1024) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ   NOT NULL,
+	expires_at TIMESTAMPTZ   NULL,
+	revoked_at TIMESTAMPTZ   NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX api_keys_user ON api_keys (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE sessions(
+	id           CHAR(22)     NOT NULL PRIMARY KEY,
+	user_id      INTEGER      NOT NULL,
+	user_agent   VARCHAR(255) NOT NULL DEFAULT '',
+	ip           VARCHAR(45)  NOT NULL DEFAULT '',
+	created_at   TIMESTAMPTZ  NOT NULL,
+	last_seen_at TIMESTAMPTZ  NOT NULL,
+	expires_at   TIMESTAMPTZ  NOT NULL,
+	revoked_at   TIMESTAMPTZ  NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX sessions_user ON sessions (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE login_history(
+	id         SERIAL       NOT NULL PRIMARY KEY,
+	user_id    INTEGER      NULL,
+	email      VARCHAR(100) NOT NULL,
+	ip         VARCHAR(45)  NOT NULL DEFAULT '',
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	succeeded  BOOLEAN      NOT NULL,
+	reason     VARCHAR(30)  NOT NULL,
+	created_at TIMESTAMPTZ  NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX login_history_user ON login_history (user_id, created_at);
@@ -0,0 +1,25 @@
+CREATE TABLE oauth_clients(
+	id            VARCHAR(32)   NOT NULL PRIMARY KEY,
+	name          VARCHAR(100)  NOT NULL,
+	secret_hash   CHAR(64)      NOT NULL DEFAULT '',
+	public        BOOLEAN       NOT NULL,
+	redirect_uris VARCHAR(5200) NOT NULL DEFAULT '',
+	grant_types   VARCHAR(100)  NOT NULL,
+	scopes        VARCHAR(1024) NOT NULL DEFAULT '',
+	created_at    TIMESTAMPTZ   NOT NULL
+);
+
+CREATE TABLE oauth_authorization_codes(
+	id             SERIAL        NOT NULL PRIMARY KEY,
+	hash           CHAR(64)      NOT NULL UNIQUE,
+	client_id      VARCHAR(32)   NOT NULL,
+	user_id        INTEGER       NOT NULL,
+	redirect_uri   VARCHAR(512)  NOT NULL,
+	scopes         VARCHAR(1024) NOT NULL DEFAULT '',
+	code_challenge VARCHAR(128)  NOT NULL,
+	nonce          VARCHAR(512)  NOT NULL DEFAULT '',
+	expires_at     TIMESTAMPTZ   NOT NULL,
+	used_at        TIMESTAMPTZ   NULL,
+	FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,89 @@
+package mocks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedAPIKeyRepository implements interfaces.APIKeyRepository
+var _ interfaces.APIKeyRepository = (*MockedAPIKeyRepository)(nil)
+
+// MockedAPIKeyRepository numbers keys from 1, like AUTO_INCREMENT does.
+type MockedAPIKeyRepository struct {
+	IdCounter int
+	Keys      []*dtos.APIKey
+}
+
+func NewMockedAPIKeyRepository() *MockedAPIKeyRepository {
+	return &MockedAPIKeyRepository{
+		IdCounter: 1,
+		Keys:      make([]*dtos.APIKey, 0, 5),
+	}
+}
+
+func (r *MockedAPIKeyRepository) CreateAPIKey(key *dtos.APIKey) (*dtos.APIKey, *utils.ErrorCode) {
+	for _, k := range r.Keys {
+		if k.Hash == key.Hash {
+			return nil, utils.NewErrorCodeString(http.StatusInternalServerError, "hash already exist")
+		}
+	}
+
+	newKey := *key
+	newKey.ID = r.IdCounter
+
+	r.IdCounter++
+
+	r.Keys = append(r.Keys, &newKey)
+
+	created := newKey
+	return &created, nil
+}
+
+func (r MockedAPIKeyRepository) SelectAPIKeyFromHash(hash string) (*dtos.APIKey, *utils.ErrorCode) {
+	for _, key := range r.Keys {
+		if key.Hash == hash {
+			selected := *key
+			return &selected, nil
+		}
+	}
+
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "hash not found")
+}
+
+func (r MockedAPIKeyRepository) SelectUserAPIKeys(userId int) ([]*dtos.APIKey, *utils.ErrorCode) {
+	keys := make([]*dtos.APIKey, 0)
+
+	for _, key := range r.Keys {
+		if key.UserID == userId && key.RevokedAt == nil {
+			selected := *key
+			keys = append(keys, &selected)
+		}
+	}
+
+	return keys, nil
+}
+
+func (r *MockedAPIKeyRepository) RevokeAPIKey(userId int, id int, revokedAt time.Time) *utils.ErrorCode {
+	for _, key := range r.Keys {
+		if key.ID == id && key.UserID == userId && key.RevokedAt == nil {
+			key.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusNotFound, "API key not found")
+}
+
+func (r *MockedAPIKeyRepository) RevokeUserAPIKeys(userId int, revokedAt time.Time) *utils.ErrorCode {
+	for _, key := range r.Keys {
+		if key.UserID == userId && key.RevokedAt == nil {
+			key.RevokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
@@ -3,25 +3,149 @@ package mocks
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/d1360-64rc14/simple-api/dtos"
 	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
 )
 
 // MockedAuthenticator implements interfaces.Authenticator
 var _ interfaces.Authenticator = (*MockedAuthenticator)(nil)
 
-type MockedAuthenticator struct{}
+// MockedAuthenticator accepts tokens formatted as "valid-for(<id>)[<email>]",
+// rejects "expired-for(<id>)[<email>]" ones as expired and any other token as
+// malformed, unless an identity was injected for it.
+//
+// Generated tokens with scopes are suffixed with "{<scopes>}", those of a
+// session with "<<session id>>", and those of an OAuth client with
+// "|<client id>". Generating the same token again gives it a new TokenID, so
+// it's no longer revoked.
+type MockedAuthenticator struct {
+	Identities    map[string]*dtos.TokenClaims
+	RevokedTokens map[string]bool
+	RevokedUsers  map[int]bool
+	IssuedCount   int
+	IDTokens      map[string]*dtos.IDToken
+}
 
 func NewMockedAuthenticator() *MockedAuthenticator {
-	return &MockedAuthenticator{}
+	return &MockedAuthenticator{
+		Identities:    make(map[string]*dtos.TokenClaims),
+		RevokedTokens: make(map[string]bool),
+		RevokedUsers:  make(map[int]bool),
+		IDTokens:      make(map[string]*dtos.IDToken),
+	}
+}
+
+// InjectIdentity makes ParseToken return the given claims for token.
+func (a *MockedAuthenticator) InjectIdentity(token string, claims *dtos.TokenClaims) {
+	if claims.TokenID == "" {
+		claims.TokenID = token
+	}
+
+	a.Identities[token] = claims
 }
 
-func (*MockedAuthenticator) GenerateToken(id int, email string) (string, error) {
-	token := fmt.Sprintf("valid-for(%d)[%s]", id, email)
+func (a *MockedAuthenticator) GenerateToken(claims *dtos.TokenClaims) (string, error) {
+	token := fmt.Sprintf("valid-for(%d)[%s]", claims.UserID, claims.Email)
+	if len(claims.Scopes) > 0 {
+		token += fmt.Sprintf("{%s}", strings.Join(claims.Scopes, " "))
+	}
+	if claims.SessionID != "" {
+		token += fmt.Sprintf("<%s>", claims.SessionID)
+	}
+	if claims.ClientID != "" {
+		token += "|" + claims.ClientID
+	}
+
+	a.IssuedCount++
+
+	claims.TokenID = fmt.Sprintf("%s#%d", token, a.IssuedCount)
+	claims.IssuedAt = time.Now()
+	if claims.ExpiresAt.IsZero() {
+		claims.ExpiresAt = claims.IssuedAt.Add(time.Hour)
+	}
+
+	identity := *claims
+	a.Identities[token] = &identity
 
 	return token, nil
 }
 
-func (*MockedAuthenticator) IsTokenValid(inputToken string) bool {
-	return strings.HasPrefix(inputToken, "valid")
+// GenerateIDToken returns "id-token(<subject>)[<audience>]", keeping the
+// idToken in IDTokens.
+func (a *MockedAuthenticator) GenerateIDToken(idToken *dtos.IDToken) (string, error) {
+	token := fmt.Sprintf("id-token(%s)[%s]", idToken.Subject, idToken.Audience)
+
+	generated := *idToken
+	a.IDTokens[token] = &generated
+
+	return token, nil
+}
+
+func (a *MockedAuthenticator) ParseToken(inputToken string) (*dtos.TokenClaims, error) {
+	claims, ok := a.Identities[inputToken]
+	if !ok {
+		var err error
+
+		claims, err = parseMockedToken(inputToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.RevokedTokens[claims.TokenID] || a.RevokedUsers[claims.UserID] {
+		return nil, utils.ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (a *MockedAuthenticator) RevokeToken(claims *dtos.TokenClaims) error {
+	a.RevokedTokens[claims.TokenID] = true
+
+	return nil
+}
+
+func (a *MockedAuthenticator) RevokeUserTokens(userId int) error {
+	a.RevokedUsers[userId] = true
+
+	return nil
+}
+
+func (*MockedAuthenticator) JSONWebKeySet() *dtos.JSONWebKeySet {
+	return &dtos.JSONWebKeySet{
+		Keys: []dtos.JSONWebKey{},
+	}
+}
+
+func parseMockedToken(inputToken string) (*dtos.TokenClaims, error) {
+	state, _, found := strings.Cut(inputToken, "-for(")
+	if !found {
+		return nil, utils.ErrTokenMalformed
+	}
+
+	var id int
+	var email string
+
+	_, err := fmt.Sscanf(inputToken, state+"-for(%d)[%s", &id, &email)
+	if err != nil || !strings.HasSuffix(email, "]") {
+		return nil, utils.ErrTokenMalformed
+	}
+
+	switch state {
+	case "valid":
+	case "expired":
+		return nil, utils.ErrTokenExpired
+	default:
+		return nil, utils.ErrTokenMalformed
+	}
+
+	return &dtos.TokenClaims{
+		UserID:    id,
+		Email:     strings.TrimSuffix(email, "]"),
+		TokenID:   inputToken,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil
 }
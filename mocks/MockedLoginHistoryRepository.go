@@ -0,0 +1,96 @@
+package mocks
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedLoginHistoryRepository implements interfaces.LoginHistoryRepository
+var _ interfaces.LoginHistoryRepository = (*MockedLoginHistoryRepository)(nil)
+
+type MockedLoginHistoryRepository struct {
+	IdCounter int
+	Attempts  []*dtos.LoginAttempt
+}
+
+func NewMockedLoginHistoryRepository() *MockedLoginHistoryRepository {
+	return &MockedLoginHistoryRepository{
+		IdCounter: 1,
+		Attempts:  make([]*dtos.LoginAttempt, 0, 5),
+	}
+}
+
+func (r *MockedLoginHistoryRepository) CreateLoginAttempt(attempt *dtos.LoginAttempt) *utils.ErrorCode {
+	newAttempt := *attempt
+	newAttempt.ID = r.IdCounter
+
+	r.IdCounter++
+
+	r.Attempts = append(r.Attempts, &newAttempt)
+
+	return nil
+}
+
+// SelectUserLoginAttempts returns the attempts of the user selected by
+// query, newest first.
+func (r MockedLoginHistoryRepository) SelectUserLoginAttempts(userId int, query *dtos.LoginHistoryQuery) ([]*dtos.LoginAttempt, int, *utils.ErrorCode) {
+	userAttempts := make([]*dtos.LoginAttempt, 0)
+
+	for _, attempt := range r.Attempts {
+		if attempt.UserID != nil && *attempt.UserID == userId {
+			selected := *attempt
+			userAttempts = append(userAttempts, &selected)
+		}
+	}
+
+	descending := query.Cursor == nil || !query.Cursor.Backward
+
+	// before tells if a comes first in the direction of the scan.
+	before := func(a, b *dtos.LoginAttempt) bool {
+		if descending {
+			a, b = b, a
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	}
+
+	sort.SliceStable(userAttempts, func(i, j int) bool {
+		return before(userAttempts[i], userAttempts[j])
+	})
+
+	total := len(userAttempts)
+
+	if query.Cursor != nil {
+		createdAt, err := time.Parse(time.RFC3339Nano, query.Cursor.Key)
+		if err != nil {
+			return nil, 0, utils.NewErrorCodeString(http.StatusBadRequest, "The cursor in the query is invalid")
+		}
+		position := &dtos.LoginAttempt{ID: query.Cursor.ID, CreatedAt: createdAt}
+
+		past := make([]*dtos.LoginAttempt, 0, len(userAttempts))
+		for _, attempt := range userAttempts {
+			if before(position, attempt) {
+				past = append(past, attempt)
+			}
+		}
+		userAttempts = past
+	}
+
+	if query.Offset >= len(userAttempts) {
+		return []*dtos.LoginAttempt{}, total, nil
+	}
+
+	end := query.Offset + query.Limit
+	if end > len(userAttempts) {
+		end = len(userAttempts)
+	}
+
+	return userAttempts[query.Offset:end], total, nil
+}
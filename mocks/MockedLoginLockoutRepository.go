@@ -0,0 +1,78 @@
+package mocks
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedLoginLockoutRepository implements interfaces.LoginLockoutRepository
+var _ interfaces.LoginLockoutRepository = (*MockedLoginLockoutRepository)(nil)
+
+type MockedLoginLockoutRepository struct {
+	Lockouts map[string]*dtos.LoginLockout
+}
+
+func NewMockedLoginLockoutRepository() *MockedLoginLockoutRepository {
+	return &MockedLoginLockoutRepository{
+		Lockouts: make(map[string]*dtos.LoginLockout),
+	}
+}
+
+func lockoutKey(kind string, subject string) string {
+	return kind + ":" + subject
+}
+
+func (r MockedLoginLockoutRepository) SelectLoginLockout(kind string, subject string) (*dtos.LoginLockout, *utils.ErrorCode) {
+	lockout, ok := r.Lockouts[lockoutKey(kind, subject)]
+	if !ok {
+		return nil, utils.NewErrorCodeString(http.StatusNotFound, "lockout not found")
+	}
+
+	selected := *lockout
+	return &selected, nil
+}
+
+func (r MockedLoginLockoutRepository) SelectActiveLoginLockouts(now time.Time) ([]*dtos.LoginLockout, *utils.ErrorCode) {
+	lockouts := make([]*dtos.LoginLockout, 0)
+
+	for _, lockout := range r.Lockouts {
+		if lockout.LockedUntil != nil && lockout.LockedUntil.After(now) {
+			selected := *lockout
+			lockouts = append(lockouts, &selected)
+		}
+	}
+
+	return lockouts, nil
+}
+
+func (r *MockedLoginLockoutRepository) UpdateLoginLockout(kind string, subject string, now time.Time, update func(lockout *dtos.LoginLockout)) *utils.ErrorCode {
+	key := lockoutKey(kind, subject)
+
+	lockout, ok := r.Lockouts[key]
+	if !ok {
+		lockout = &dtos.LoginLockout{Kind: kind, Subject: subject, LastFailureAt: now}
+	}
+
+	updated := *lockout
+	update(&updated)
+	r.Lockouts[key] = &updated
+
+	return nil
+}
+
+func (r *MockedLoginLockoutRepository) RemoveLoginLockout(kind string, subject string) *utils.ErrorCode {
+	key := lockoutKey(kind, subject)
+
+	if _, ok := r.Lockouts[key]; !ok {
+		return utils.NewErrorCodeString(http.StatusNotFound, fmt.Sprintf("No failed logins for %s '%s'", kind, subject))
+	}
+
+	delete(r.Lockouts, key)
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package mocks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedMFARepository implements interfaces.MFARepository
+var _ interfaces.MFARepository = (*MockedMFARepository)(nil)
+
+type MockedMFARepository struct {
+	TOTPs         map[int]*dtos.UserTOTP
+	RecoveryCodes map[int]map[string]bool
+}
+
+func NewMockedMFARepository() *MockedMFARepository {
+	return &MockedMFARepository{
+		TOTPs:         make(map[int]*dtos.UserTOTP),
+		RecoveryCodes: make(map[int]map[string]bool),
+	}
+}
+
+func (r MockedMFARepository) SelectUserTOTP(userId int) (*dtos.UserTOTP, *utils.ErrorCode) {
+	totp, ok := r.TOTPs[userId]
+	if !ok {
+		return nil, utils.NewErrorCodeString(http.StatusNotFound, "totp not found")
+	}
+
+	selected := *totp
+	return &selected, nil
+}
+
+func (r *MockedMFARepository) SaveUserTOTP(userId int, secret string) *utils.ErrorCode {
+	r.TOTPs[userId] = &dtos.UserTOTP{
+		UserID: userId,
+		Secret: secret,
+	}
+
+	return nil
+}
+
+func (r *MockedMFARepository) ConfirmUserTOTP(userId int, confirmedAt time.Time) *utils.ErrorCode {
+	if totp, ok := r.TOTPs[userId]; ok {
+		totp.ConfirmedAt = &confirmedAt
+	}
+
+	return nil
+}
+
+func (r *MockedMFARepository) UseTOTPStep(userId int, step int64) (bool, *utils.ErrorCode) {
+	totp, ok := r.TOTPs[userId]
+	if !ok || totp.LastUsedStep >= step {
+		return false, nil
+	}
+
+	totp.LastUsedStep = step
+	return true, nil
+}
+
+func (r *MockedMFARepository) RemoveUserTOTP(userId int) *utils.ErrorCode {
+	delete(r.TOTPs, userId)
+	delete(r.RecoveryCodes, userId)
+
+	return nil
+}
+
+// ReplaceRecoveryCodes keeps unused codes as true.
+func (r *MockedMFARepository) ReplaceRecoveryCodes(userId int, hashes []string) *utils.ErrorCode {
+	codes := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		codes[hash] = true
+	}
+
+	r.RecoveryCodes[userId] = codes
+
+	return nil
+}
+
+func (r *MockedMFARepository) UseRecoveryCode(userId int, hash string, usedAt time.Time) (bool, *utils.ErrorCode) {
+	if !r.RecoveryCodes[userId][hash] {
+		return false, nil
+	}
+
+	r.RecoveryCodes[userId][hash] = false
+	return true, nil
+}
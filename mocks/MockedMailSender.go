@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+// MockedMailSender implements interfaces.MailSender
+var _ interfaces.MailSender = (*MockedMailSender)(nil)
+
+type MockedMailSender struct {
+	Sent []*dtos.Mail
+}
+
+func NewMockedMailSender() *MockedMailSender {
+	return &MockedMailSender{
+		Sent: make([]*dtos.Mail, 0, 5),
+	}
+}
+
+func (s *MockedMailSender) SendMail(mail *dtos.Mail) error {
+	sent := *mail
+	s.Sent = append(s.Sent, &sent)
+
+	return nil
+}
+
+// LastTo returns the last mail sent to the given address, or nil.
+func (s MockedMailSender) LastTo(address string) *dtos.Mail {
+	for i := len(s.Sent) - 1; i >= 0; i-- {
+		if s.Sent[i].To == address {
+			return s.Sent[i]
+		}
+	}
+
+	return nil
+}
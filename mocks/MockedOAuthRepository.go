@@ -0,0 +1,127 @@
+package mocks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedOAuthRepository implements interfaces.OAuthRepository
+var _ interfaces.OAuthRepository = (*MockedOAuthRepository)(nil)
+
+// MockedOAuthRepository numbers authorization codes from 1, like
+// AUTO_INCREMENT does.
+type MockedOAuthRepository struct {
+	IdCounter int
+	Clients   []*dtos.OAuthClient
+	Codes     []*dtos.AuthorizationCode
+}
+
+func NewMockedOAuthRepository() *MockedOAuthRepository {
+	return &MockedOAuthRepository{
+		IdCounter: 1,
+		Clients:   make([]*dtos.OAuthClient, 0, 5),
+		Codes:     make([]*dtos.AuthorizationCode, 0, 5),
+	}
+}
+
+func (r *MockedOAuthRepository) CreateOAuthClient(client *dtos.OAuthClient) *utils.ErrorCode {
+	for _, c := range r.Clients {
+		if c.ID == client.ID {
+			return utils.NewErrorCodeString(http.StatusInternalServerError, "client id already exist")
+		}
+	}
+
+	newClient := *client
+	r.Clients = append(r.Clients, &newClient)
+
+	return nil
+}
+
+func (r MockedOAuthRepository) SelectOAuthClient(clientId string) (*dtos.OAuthClient, *utils.ErrorCode) {
+	for _, client := range r.Clients {
+		if client.ID == clientId {
+			selected := *client
+			return &selected, nil
+		}
+	}
+
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "client not found")
+}
+
+func (r MockedOAuthRepository) SelectOAuthClients() ([]*dtos.OAuthClient, *utils.ErrorCode) {
+	clients := make([]*dtos.OAuthClient, 0, len(r.Clients))
+
+	for _, client := range r.Clients {
+		selected := *client
+		clients = append(clients, &selected)
+	}
+
+	return clients, nil
+}
+
+func (r *MockedOAuthRepository) RemoveOAuthClient(clientId string) *utils.ErrorCode {
+	for i, client := range r.Clients {
+		if client.ID == clientId {
+			r.Clients = append(r.Clients[:i], r.Clients[i+1:]...)
+
+			codes := make([]*dtos.AuthorizationCode, 0, len(r.Codes))
+			for _, code := range r.Codes {
+				if code.ClientID != clientId {
+					codes = append(codes, code)
+				}
+			}
+			r.Codes = codes
+
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusNotFound, "OAuth client not found")
+}
+
+func (r *MockedOAuthRepository) CreateAuthorizationCode(code *dtos.AuthorizationCode) *utils.ErrorCode {
+	for _, c := range r.Codes {
+		if c.Hash == code.Hash {
+			return utils.NewErrorCodeString(http.StatusInternalServerError, "hash already exist")
+		}
+	}
+
+	newCode := *code
+	newCode.ID = r.IdCounter
+
+	r.IdCounter++
+
+	r.Codes = append(r.Codes, &newCode)
+
+	return nil
+}
+
+func (r MockedOAuthRepository) SelectAuthorizationCodeFromHash(hash string) (*dtos.AuthorizationCode, *utils.ErrorCode) {
+	for _, code := range r.Codes {
+		if code.Hash == hash {
+			selected := *code
+			return &selected, nil
+		}
+	}
+
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "hash not found")
+}
+
+func (r *MockedOAuthRepository) UseAuthorizationCode(id int, usedAt time.Time) (bool, *utils.ErrorCode) {
+	for _, code := range r.Codes {
+		if code.ID == id {
+			if code.UsedAt != nil {
+				return false, nil
+			}
+
+			code.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,84 @@
+package mocks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedRefreshTokenRepository implements interfaces.RefreshTokenRepository
+var _ interfaces.RefreshTokenRepository = (*MockedRefreshTokenRepository)(nil)
+
+type MockedRefreshTokenRepository struct {
+	IdCounter int
+	Tokens    []*dtos.RefreshToken
+}
+
+func NewMockedRefreshTokenRepository() *MockedRefreshTokenRepository {
+	return &MockedRefreshTokenRepository{
+		IdCounter: 0,
+		Tokens:    make([]*dtos.RefreshToken, 0, 5),
+	}
+}
+
+func (r *MockedRefreshTokenRepository) CreateRefreshToken(token *dtos.RefreshToken) *utils.ErrorCode {
+	for _, t := range r.Tokens {
+		if t.Hash == token.Hash {
+			return utils.NewErrorCodeString(http.StatusInternalServerError, "hash already exist")
+		}
+	}
+
+	newToken := *token
+	newToken.ID = r.IdCounter
+
+	r.IdCounter++
+
+	r.Tokens = append(r.Tokens, &newToken)
+
+	return nil
+}
+
+func (r MockedRefreshTokenRepository) SelectRefreshTokenFromHash(hash string) (*dtos.RefreshToken, *utils.ErrorCode) {
+	for _, token := range r.Tokens {
+		if token.Hash == hash {
+			selected := *token
+			return &selected, nil
+		}
+	}
+
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "hash not found")
+}
+
+func (r *MockedRefreshTokenRepository) UseRefreshToken(id int, usedAt time.Time) (bool, *utils.ErrorCode) {
+	for _, token := range r.Tokens {
+		if token.ID == id && token.UsedAt == nil && token.RevokedAt == nil {
+			token.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *MockedRefreshTokenRepository) RevokeRefreshTokenFamily(familyId string, revokedAt time.Time) *utils.ErrorCode {
+	for _, token := range r.Tokens {
+		if token.FamilyID == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
+
+func (r *MockedRefreshTokenRepository) RevokeUserRefreshTokens(userId int, revokedAt time.Time) *utils.ErrorCode {
+	for _, token := range r.Tokens {
+		if token.UserID == userId && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package mocks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedSessionRepository implements interfaces.SessionRepository
+var _ interfaces.SessionRepository = (*MockedSessionRepository)(nil)
+
+type MockedSessionRepository struct {
+	Sessions []*dtos.Session
+}
+
+func NewMockedSessionRepository() *MockedSessionRepository {
+	return &MockedSessionRepository{
+		Sessions: make([]*dtos.Session, 0, 5),
+	}
+}
+
+func (r *MockedSessionRepository) CreateSession(session *dtos.Session) *utils.ErrorCode {
+	for _, s := range r.Sessions {
+		if s.ID == session.ID {
+			return utils.NewErrorCodeString(http.StatusInternalServerError, "id already exist")
+		}
+	}
+
+	newSession := *session
+	r.Sessions = append(r.Sessions, &newSession)
+
+	return nil
+}
+
+func (r MockedSessionRepository) SelectSession(id string) (*dtos.Session, *utils.ErrorCode) {
+	for _, session := range r.Sessions {
+		if session.ID == id {
+			selected := *session
+			return &selected, nil
+		}
+	}
+
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "session not found")
+}
+
+func (r MockedSessionRepository) SelectUserSessions(userId int, now time.Time) ([]*dtos.Session, *utils.ErrorCode) {
+	sessions := make([]*dtos.Session, 0)
+
+	for _, session := range r.Sessions {
+		if session.UserID == userId && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			selected := *session
+			sessions = append(sessions, &selected)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *MockedSessionRepository) TouchSession(id string, lastSeenAt time.Time, expiresAt time.Time) *utils.ErrorCode {
+	for _, session := range r.Sessions {
+		if session.ID == id {
+			session.LastSeenAt = lastSeenAt
+			session.ExpiresAt = expiresAt
+		}
+	}
+
+	return nil
+}
+
+func (r *MockedSessionRepository) RevokeSession(userId int, id string, revokedAt time.Time) *utils.ErrorCode {
+	for _, session := range r.Sessions {
+		if session.ID == id && session.UserID == userId && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusNotFound, "Session not found")
+}
+
+func (r *MockedSessionRepository) RevokeUserSessions(userId int, revokedAt time.Time) *utils.ErrorCode {
+	for _, session := range r.Sessions {
+		if session.UserID == userId && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedTokenRevocationRepository implements interfaces.TokenRevocationRepository
+var _ interfaces.TokenRevocationRepository = (*MockedTokenRevocationRepository)(nil)
+
+// MockedTokenRevocationRepository is an in-memory revocation store.
+type MockedTokenRevocationRepository struct {
+	RevokedTokens map[string]time.Time
+	IssuedBefore  map[int]time.Time
+}
+
+func NewMockedTokenRevocationRepository() *MockedTokenRevocationRepository {
+	return &MockedTokenRevocationRepository{
+		RevokedTokens: make(map[string]time.Time),
+		IssuedBefore:  make(map[int]time.Time),
+	}
+}
+
+func (r *MockedTokenRevocationRepository) RevokeToken(tokenId string, expiresAt time.Time, now time.Time) *utils.ErrorCode {
+	r.RevokedTokens[tokenId] = expiresAt
+
+	return nil
+}
+
+func (r *MockedTokenRevocationRepository) RevokeUserTokens(userId int, issuedBefore time.Time) *utils.ErrorCode {
+	if current, ok := r.IssuedBefore[userId]; !ok || issuedBefore.After(current) {
+		r.IssuedBefore[userId] = issuedBefore
+	}
+
+	return nil
+}
+
+func (r MockedTokenRevocationRepository) IsTokenRevoked(tokenId string, userId int, issuedAt time.Time) (bool, *utils.ErrorCode) {
+	if _, ok := r.RevokedTokens[tokenId]; ok {
+		return true, nil
+	}
+
+	if issuedBefore, ok := r.IssuedBefore[userId]; ok && issuedAt.Before(issuedBefore) {
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -3,9 +3,13 @@ package mocks
 import (
 	"errors"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/d1360-64rc14/simple-api/dtos"
 	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
 	"github.com/d1360-64rc14/simple-api/utils"
 )
 
@@ -16,6 +20,7 @@ type MockedUserRepository struct {
 	IdCounter int
 	Closed    bool
 	Users     []*dtos.IdentifiedUserWithHash
+	Roles     map[int][]string
 }
 
 func NewMockedUserRepository() *MockedUserRepository {
@@ -23,6 +28,7 @@ func NewMockedUserRepository() *MockedUserRepository {
 		IdCounter: 0,
 		Closed:    false,
 		Users:     make([]*dtos.IdentifiedUserWithHash, 0, 5),
+		Roles:     make(map[int][]string),
 	}
 }
 
@@ -31,6 +37,7 @@ func NewMockedUserRepositoryWith(idCounter int, users []*dtos.IdentifiedUserWith
 		IdCounter: idCounter,
 		Closed:    false,
 		Users:     users,
+		Roles:     make(map[int][]string),
 	}
 }
 
@@ -65,6 +72,7 @@ func (r *MockedUserRepository) CreateUser(user *dtos.UserWithHash) (*dtos.Identi
 	r.IdCounter++
 
 	r.Users = append(r.Users, newUser)
+	r.Roles[newUser.ID] = []string{models.RoleUser}
 
 	return &newUser.IdentifiedUser, nil
 }
@@ -87,6 +95,8 @@ func (r *MockedUserRepository) RemoveUser(id int) *utils.ErrorCode {
 		return utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
 	}
 
+	delete(r.Roles, id)
+
 	if len(r.Users) == 1 {
 		r.Users = r.Users[:0]
 		return nil
@@ -100,19 +110,142 @@ func (r *MockedUserRepository) RemoveUser(id int) *utils.ErrorCode {
 }
 
 func (r MockedUserRepository) SelectAllUsers() ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
+	users, _, errC := r.SelectUsers(&dtos.UserQuery{Limit: len(r.Users), Sort: "id"})
+
+	return users, errC
+}
+
+func (r MockedUserRepository) SelectUsers(query *dtos.UserQuery) ([]*dtos.IdentifiedUser, int, *utils.ErrorCode) {
+	if r.Closed {
+		return nil, 0, utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	users := make([]*dtos.IdentifiedUser, 0, len(r.Users))
+
+	for _, user := range r.Users {
+		if query.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(user.Email), "@"+strings.ToLower(query.EmailDomain)) {
+			continue
+		}
+		if query.UsernamePrefix != "" && !strings.HasPrefix(strings.ToLower(user.UserName), strings.ToLower(query.UsernamePrefix)) {
+			continue
+		}
+
+		identifiedUser := user.IdentifiedUser
+		users = append(users, &identifiedUser)
+	}
+
+	descending := strings.HasPrefix(query.Sort, "-")
+	byUsername := strings.TrimPrefix(query.Sort, "-") == "username"
+	if query.Cursor != nil && query.Cursor.Backward {
+		descending = !descending
+	}
+
+	// before tells if a comes first in the direction of the scan.
+	before := func(a, b *dtos.IdentifiedUser) bool {
+		if descending {
+			a, b = b, a
+		}
+		if byUsername && a.UserName != b.UserName {
+			return a.UserName < b.UserName
+		}
+		return a.ID < b.ID
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		return before(users[i], users[j])
+	})
+
+	total := len(users)
+
+	if query.Cursor != nil {
+		position := &dtos.IdentifiedUser{ID: query.Cursor.ID}
+		position.UserName = query.Cursor.Key
+
+		past := make([]*dtos.IdentifiedUser, 0, len(users))
+		for _, user := range users {
+			if before(position, user) {
+				past = append(past, user)
+			}
+		}
+		users = past
+	}
+
+	if query.Offset >= len(users) {
+		return []*dtos.IdentifiedUser{}, total, nil
+	}
+
+	end := query.Offset + query.Limit
+	if end > len(users) {
+		end = len(users)
+	}
+
+	return users[query.Offset:end], total, nil
+}
+
+// SearchUsers ranks users having words starting with every term in their
+// username first, as MySQL does with its FULLTEXT index.
+func (r MockedUserRepository) SearchUsers(query *dtos.UserSearchQuery) ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
 	if r.Closed {
 		return nil, utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
 	}
 
-	users := make([]*dtos.IdentifiedUser, len(r.Users))
+	terms := query.Terms()
+	users := make([]*dtos.IdentifiedUser, 0, len(r.Users))
+	scores := make(map[int]int, len(r.Users))
 
-	for i, user := range r.Users {
-		users[i] = &user.IdentifiedUser
+	for _, user := range r.Users {
+		usernameWords := dtos.UserSearchQuery{Q: user.UserName}.Terms()
+		emailWords := dtos.UserSearchQuery{Q: user.Email}.Terms()
+
+		score := 0
+		for _, term := range terms {
+			inUsername := hasWordWithPrefix(usernameWords, term)
+			if !inUsername && !hasWordWithPrefix(emailWords, term) {
+				score = -1
+				break
+			}
+			if inUsername {
+				score++
+			}
+		}
+
+		if score < 0 {
+			continue
+		}
+
+		identifiedUser := user.IdentifiedUser
+		users = append(users, &identifiedUser)
+		scores[user.ID] = score
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		a, b := users[i], users[j]
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+		if a.UserName != b.UserName {
+			return a.UserName < b.UserName
+		}
+		return a.ID < b.ID
+	})
+
+	if len(users) > query.Limit {
+		users = users[:query.Limit]
 	}
 
 	return users, nil
 }
 
+func hasWordWithPrefix(words []string, prefix string) bool {
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (r MockedUserRepository) SelectCompleteUserFromId(id int) (*dtos.IdentifiedUserWithHash, *utils.ErrorCode) {
 	if r.Closed {
 		return nil, utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
@@ -138,7 +271,7 @@ func (r MockedUserRepository) SelectUserFromEmail(email string) (*dtos.Identifie
 		}
 	}
 
-	return nil, utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "email not found")
 }
 
 func (r MockedUserRepository) SelectUserFromId(id int) (*dtos.IdentifiedUser, *utils.ErrorCode) {
@@ -152,7 +285,7 @@ func (r MockedUserRepository) SelectUserFromId(id int) (*dtos.IdentifiedUser, *u
 		}
 	}
 
-	return nil, utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "id not found")
 }
 
 func (r MockedUserRepository) SelectUserHashFromId(id int) (string, *utils.ErrorCode) {
@@ -184,6 +317,73 @@ func (r *MockedUserRepository) UpdateUsername(id int, newUsername string) *utils
 	return utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
 }
 
+func (r *MockedUserRepository) UpdateUserHash(id int, newHash string) *utils.ErrorCode {
+	if r.Closed {
+		return utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	for _, user := range r.Users {
+		if user.ID == id {
+			user.Hash = newHash
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
+}
+
+func (r *MockedUserRepository) VerifyUserEmail(id int, verifiedAt time.Time) *utils.ErrorCode {
+	if r.Closed {
+		return utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	for _, user := range r.Users {
+		if user.ID == id {
+			user.EmailVerified = true
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
+}
+
+func (r *MockedUserRepository) UpdateUserLastLogin(id int, lastLoginAt time.Time) *utils.ErrorCode {
+	if r.Closed {
+		return utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	for _, user := range r.Users {
+		if user.ID == id {
+			user.LastLoginAt = &lastLoginAt
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
+}
+
+func (r *MockedUserRepository) UpdateUserEmail(id int, newEmail string, verifiedAt time.Time) *utils.ErrorCode {
+	if r.Closed {
+		return utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	for _, user := range r.Users {
+		if user.Email == newEmail && user.ID != id {
+			return utils.NewErrorCodeString(http.StatusConflict, "email already exist")
+		}
+	}
+
+	for _, user := range r.Users {
+		if user.ID == id {
+			user.Email = newEmail
+			user.EmailVerified = true
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusBadRequest, "id not found")
+}
+
 func (r MockedUserRepository) UserExist(id int) (bool, *utils.ErrorCode) {
 	if r.Closed {
 		return false, utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
@@ -197,3 +397,47 @@ func (r MockedUserRepository) UserExist(id int) (bool, *utils.ErrorCode) {
 
 	return false, nil
 }
+
+func (r MockedUserRepository) SelectUserRoles(id int) ([]string, *utils.ErrorCode) {
+	if r.Closed {
+		return nil, utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	roles := make([]string, len(r.Roles[id]))
+	copy(roles, r.Roles[id])
+
+	return roles, nil
+}
+
+func (r *MockedUserRepository) AddUserRole(id int, role string) *utils.ErrorCode {
+	if r.Closed {
+		return utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	for _, existing := range r.Roles[id] {
+		if existing == role {
+			return nil
+		}
+	}
+
+	r.Roles[id] = append(r.Roles[id], role)
+
+	return nil
+}
+
+func (r *MockedUserRepository) RemoveUserRole(id int, role string) *utils.ErrorCode {
+	if r.Closed {
+		return utils.NewErrorCodeString(http.StatusInternalServerError, "repository closed")
+	}
+
+	roles := r.Roles[id]
+
+	for i, existing := range roles {
+		if existing == role {
+			r.Roles[id] = append(roles[:i:i], roles[i+1:]...)
+			return nil
+		}
+	}
+
+	return utils.NewErrorCodeString(http.StatusNotFound, "role not found")
+}
@@ -0,0 +1,74 @@
+package mocks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MockedUserTokenRepository implements interfaces.UserTokenRepository
+var _ interfaces.UserTokenRepository = (*MockedUserTokenRepository)(nil)
+
+type MockedUserTokenRepository struct {
+	IdCounter int
+	Tokens    []*dtos.UserToken
+}
+
+func NewMockedUserTokenRepository() *MockedUserTokenRepository {
+	return &MockedUserTokenRepository{
+		IdCounter: 0,
+		Tokens:    make([]*dtos.UserToken, 0, 5),
+	}
+}
+
+func (r *MockedUserTokenRepository) CreateUserToken(token *dtos.UserToken) *utils.ErrorCode {
+	for _, t := range r.Tokens {
+		if t.Hash == token.Hash {
+			return utils.NewErrorCodeString(http.StatusInternalServerError, "hash already exist")
+		}
+	}
+
+	newToken := *token
+	newToken.ID = r.IdCounter
+
+	r.IdCounter++
+
+	r.Tokens = append(r.Tokens, &newToken)
+
+	return nil
+}
+
+func (r MockedUserTokenRepository) SelectUserTokenFromHash(purpose string, hash string) (*dtos.UserToken, *utils.ErrorCode) {
+	for _, token := range r.Tokens {
+		if token.Purpose == purpose && token.Hash == hash {
+			selected := *token
+			return &selected, nil
+		}
+	}
+
+	return nil, utils.NewErrorCodeString(http.StatusNotFound, "hash not found")
+}
+
+func (r *MockedUserTokenRepository) UseUserToken(id int, usedAt time.Time) (bool, *utils.ErrorCode) {
+	for _, token := range r.Tokens {
+		if token.ID == id && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *MockedUserTokenRepository) InvalidateUserTokens(userId int, purpose string, invalidatedAt time.Time) *utils.ErrorCode {
+	for _, token := range r.Tokens {
+		if token.UserID == userId && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &invalidatedAt
+		}
+	}
+
+	return nil
+}
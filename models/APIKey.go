@@ -0,0 +1,9 @@
+package models
+
+const (
+	// APIKeyPrefix starts every API key, telling them apart from JWTs.
+	APIKeyPrefix = "sak_"
+	// APIKeyVisibleLength is how many leading characters of an API key are
+	// stored in clear, for users to recognize their keys.
+	APIKeyVisibleLength = len(APIKeyPrefix) + 8
+)
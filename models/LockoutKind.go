@@ -0,0 +1,8 @@
+package models
+
+const (
+	// LockoutKindAccount lockouts are keyed by the lowercase login email.
+	LockoutKindAccount = "account"
+	// LockoutKindIP lockouts are keyed by the client IP.
+	LockoutKindIP = "ip"
+)
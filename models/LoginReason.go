@@ -0,0 +1,18 @@
+package models
+
+const (
+	// LoginReasonPassword logins succeeded with the password alone.
+	LoginReasonPassword = "password"
+	// LoginReasonMFA logins succeeded with the password and a second factor.
+	LoginReasonMFA = "mfa"
+	// LoginReasonUnknownEmail logins failed on an email without account.
+	LoginReasonUnknownEmail = "unknown_email"
+	// LoginReasonInvalidPassword logins failed on a wrong password.
+	LoginReasonInvalidPassword = "invalid_password"
+	// LoginReasonInvalidMFACode logins failed on a wrong second factor code.
+	LoginReasonInvalidMFACode = "invalid_mfa_code"
+	// LoginReasonLockedOut logins were refused to a locked out account or IP.
+	LoginReasonLockedOut = "locked_out"
+	// LoginReasonEmailNotVerified logins were refused to an unverified email.
+	LoginReasonEmailNotVerified = "email_not_verified"
+)
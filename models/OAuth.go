@@ -0,0 +1,27 @@
+package models
+
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+
+	ResponseTypeCode = "code"
+
+	// CodeChallengeS256 is the only PKCE method accepted, "plain" challenges
+	// would give away the verifier.
+	CodeChallengeS256 = "S256"
+)
+
+// OAuth error codes of RFC 6749, RFC 6750, RFC 7009 and RFC 7662 responses.
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAccessDenied            = "access_denied"
+	OAuthInvalidToken            = "invalid_token"
+	OAuthInsufficientScope       = "insufficient_scope"
+	OAuthServerError             = "server_error"
+)
@@ -0,0 +1,10 @@
+package models
+
+const (
+	// RoleAdmin is allowed to do anything, including acting on behalf of any user.
+	RoleAdmin = "admin"
+	// RoleUser is given to every new user, allowing them to manage their own account.
+	RoleUser = "user"
+	// RoleReadOnly can only read users.
+	RoleReadOnly = "readonly"
+)
@@ -0,0 +1,20 @@
+package models
+
+const (
+	// ScopeMFAPending tokens only prove the password was right, they must be
+	// exchanged for access tokens along with a second factor code.
+	ScopeMFAPending = "mfa_pending"
+)
+
+// OpenID Connect scopes, granting an ID token and the claims userinfo
+// returns.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// IsOIDCScope checks if scope is one of the OpenID Connect scopes.
+func IsOIDCScope(scope string) bool {
+	return scope == ScopeOpenID || scope == ScopeProfile || scope == ScopeEmail
+}
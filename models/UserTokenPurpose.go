@@ -0,0 +1,10 @@
+package models
+
+const (
+	// PurposePasswordReset tokens let a user set a new password without the current one.
+	PurposePasswordReset = "password_reset"
+	// PurposeEmailVerification tokens confirm the user owns their email address.
+	PurposeEmailVerification = "email_verification"
+	// PurposeEmailChange tokens confirm the user owns the new email address in their payload.
+	PurposeEmailChange = "email_change"
+)
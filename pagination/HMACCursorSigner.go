@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+)
+
+// HMACCursorSigner implements CursorSigner
+var _ interfaces.CursorSigner = (*HMACCursorSigner)(nil)
+
+const cursorKeySize = 32
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// HMACCursorSigner encodes cursors as opaque tokens, authenticated with
+// HMAC-SHA256 so clients can't forge positions the API never handed out.
+type HMACCursorSigner struct {
+	key []byte
+}
+
+func NewHMACCursorSigner(settings *config.Pagination) (interfaces.CursorSigner, error) {
+	key, err := base64.RawStdEncoding.DecodeString(settings.Base64CursorKey)
+	if err != nil {
+		return nil, fmt.Errorf("cursor key: %w", err)
+	}
+
+	if len(key) != cursorKeySize {
+		return nil, fmt.Errorf("cursor key should be %d bytes wide, got %d", cursorKeySize, len(key))
+	}
+
+	return &HMACCursorSigner{key: key}, nil
+}
+
+// Sign returns the token of cursor, its JSON followed by its MAC.
+func (s HMACCursorSigner) Sign(cursor *dtos.Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	token := append(payload, s.mac(payload)...)
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Verify returns the cursor of token.
+//
+// Errors can be caused by:
+// token not being encoded by Sign;
+// token being signed with another key.
+func (s HMACCursorSigner) Verify(token string) (*dtos.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) <= sha256.Size {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, mac := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	if !hmac.Equal(mac, s.mac(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := new(dtos.Cursor)
+
+	err = json.Unmarshal(payload, cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func (s HMACCursorSigner) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,77 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+)
+
+var testPaginationSettings = &config.Pagination{
+	Base64CursorKey: "Y3Vyc29ycy1vZi10aGUtdXNlcnMtbGlzdC1zaWduZXI",
+}
+
+func TestNewHMACCursorSigner(t *testing.T) {
+	testCases := []struct {
+		key   string
+		valid bool
+	}{
+		{"Y3Vyc29ycy1vZi10aGUtdXNlcnMtbGlzdC1zaWduZXI", true},
+		{"IXRoZXF1aWNrZm94anVtcHNvdmVydGhlbGF6eWRvZyE", true},
+		{"c2hvcnQta2V5", false},
+		{"", false},
+		{"not base64!", false},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			_, err := NewHMACCursorSigner(&config.Pagination{Base64CursorKey: _case.key})
+
+			if (err == nil) != _case.valid {
+				t.Errorf("Key '%s' should be valid: %t, got error '%v'", _case.key, _case.valid, err)
+			}
+		})
+	}
+}
+
+func TestHMACCursorSigner(t *testing.T) {
+	signer, err := NewHMACCursorSigner(testPaginationSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSigner, _ := NewHMACCursorSigner(&config.Pagination{Base64CursorKey: "IXRoZXF1aWNrZm94anVtcHNvdmVydGhlbGF6eWRvZyE"})
+
+	cursor := &dtos.Cursor{Sort: "-username", Key: "diego", ID: 42, Backward: true}
+
+	token, err := signer.Sign(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(token, "diego") {
+		t.Errorf("Token should be opaque, got '%s'", token)
+	}
+
+	verified, err := signer.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *verified != *cursor {
+		t.Errorf("Verified cursor should be %+v, got %+v", cursor, verified)
+	}
+
+	otherToken, _ := otherSigner.Sign(cursor)
+	tampered := []byte(token)
+	tampered[2] ^= 1
+
+	for i, invalidToken := range []string{otherToken, string(tampered), token[:len(token)-1], "", "!!!"} {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			if _, err := signer.Verify(invalidToken); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Token '%s' should be invalid, got '%v'", invalidToken, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLAPIKeyRepository implements APIKeyRepository
+var _ interfaces.APIKeyRepository = (*MySQLAPIKeyRepository)(nil)
+
+type MySQLAPIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLAPIKeyRepository(database interfaces.Database) (interfaces.APIKeyRepository, error) {
+	return &MySQLAPIKeyRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// CreateAPIKey stores a new API key, returning it with its ID.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get the last inserted ID.
+func (r MySQLAPIKeyRepository) CreateAPIKey(key *dtos.APIKey) (*dtos.APIKey, *utils.ErrorCode) {
+	result, err := r.db.Exec(`
+		INSERT INTO api_keys(user_id, name, prefix, hash, scopes, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?);
+	`, key.UserID, key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, " "), key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	created := *key
+	created.ID = int(id)
+
+	return &created, nil
+}
+
+// SelectAPIKeyFromHash returns the API key with the given hash, even if
+// expired or revoked.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// hash not being found.
+func (r MySQLAPIKeyRepository) SelectAPIKeyFromHash(hash string) (*dtos.APIKey, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			user_id,
+			name,
+			prefix,
+			hash,
+			scopes,
+			created_at,
+			expires_at,
+			revoked_at
+		FROM
+			api_keys
+		WHERE
+			hash = ?;
+	`, hash)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return key, nil
+}
+
+// SelectUserAPIKeys returns the API keys of the user not yet revoked.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to scan a row.
+func (r MySQLAPIKeyRepository) SelectUserAPIKeys(userId int) ([]*dtos.APIKey, *utils.ErrorCode) {
+	rows, err := r.db.Query(`
+		SELECT
+			id,
+			user_id,
+			name,
+			prefix,
+			hash,
+			scopes,
+			created_at,
+			expires_at,
+			revoked_at
+		FROM
+			api_keys
+		WHERE
+			user_id = ? AND
+			revoked_at IS NULL
+		ORDER BY
+			id;
+	`, userId)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	keys := make([]*dtos.APIKey, 0)
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the API key of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// key not being found, belonging to another user or being already revoked.
+func (r MySQLAPIKeyRepository) RevokeAPIKey(userId int, id int, revokedAt time.Time) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		UPDATE
+			api_keys
+		SET
+			revoked_at = ?
+		WHERE
+			id = ? AND
+			user_id = ? AND
+			revoked_at IS NULL;
+	`, revokedAt, id, userId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, "API key not found")
+	}
+
+	return nil
+}
+
+// RevokeUserAPIKeys revokes every API key of the user not yet revoked.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLAPIKeyRepository) RevokeUserAPIKeys(userId int, revokedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			api_keys
+		SET
+			revoked_at = ?
+		WHERE
+			user_id = ? AND
+			revoked_at IS NULL;
+	`, revokedAt, userId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (*dtos.APIKey, error) {
+	key := new(dtos.APIKey)
+
+	var scopes string
+
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Hash,
+		&scopes,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+
+	return key, nil
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLLoginHistoryRepository implements LoginHistoryRepository
+var _ interfaces.LoginHistoryRepository = (*MySQLLoginHistoryRepository)(nil)
+
+type MySQLLoginHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLLoginHistoryRepository(database interfaces.Database) (interfaces.LoginHistoryRepository, error) {
+	return &MySQLLoginHistoryRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// CreateLoginAttempt appends the attempt to the login history.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLLoginHistoryRepository) CreateLoginAttempt(attempt *dtos.LoginAttempt) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO login_history(user_id, email, ip, user_agent, succeeded, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?);
+	`, attempt.UserID, attempt.Email, attempt.IP, attempt.UserAgent, attempt.Succeeded, attempt.Reason, attempt.CreatedAt)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectUserLoginAttempts returns the attempts of the user selected by
+// query, newest first, along with their total count.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r MySQLLoginHistoryRepository) SelectUserLoginAttempts(userId int, query *dtos.LoginHistoryQuery) ([]*dtos.LoginAttempt, int, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			count(*)
+		FROM
+			login_history
+		WHERE
+			user_id = ?;
+	`, userId)
+
+	if row.Err() != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	var total int
+
+	err := row.Scan(&total)
+	if err != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	order, keysetCondition, keysetArgs, errC := loginHistoryKeyset(query)
+	if errC != nil {
+		return nil, 0, errC
+	}
+
+	where := "user_id = ?"
+	args := []any{userId}
+	if keysetCondition != "" {
+		where += " AND " + keysetCondition
+		args = append(args, keysetArgs...)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT
+			id,
+			user_id,
+			email,
+			ip,
+			user_agent,
+			succeeded,
+			reason,
+			created_at
+		FROM
+			login_history
+		WHERE
+			`+where+`
+		ORDER BY
+			`+order+`
+		LIMIT ? OFFSET ?;
+	`, append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	attempts := make([]*dtos.LoginAttempt, 0, query.Limit)
+
+	for rows.Next() {
+		attempt := new(dtos.LoginAttempt)
+
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.UserID,
+			&attempt.Email,
+			&attempt.IP,
+			&attempt.UserAgent,
+			&attempt.Succeeded,
+			&attempt.Reason,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	if rows.Err() != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return attempts, total, nil
+}
+
+// loginHistoryKeyset returns the ORDER BY clause of the login history, newest
+// first and tie-broken by id, and the condition selecting the attempts past
+// the cursor of query, empty when there is none, along with its arguments.
+//
+// Errors can be caused by:
+// cursor key not being a time.
+func loginHistoryKeyset(query *dtos.LoginHistoryQuery) (string, string, []any, *utils.ErrorCode) {
+	direction, comparison := "DESC", "<"
+	if query.Cursor != nil && query.Cursor.Backward {
+		direction, comparison = "ASC", ">"
+	}
+
+	order := "created_at " + direction + ", id " + direction
+
+	if query.Cursor == nil {
+		return order, "", nil, nil
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, query.Cursor.Key)
+	if err != nil {
+		return "", "", nil, utils.NewErrorCodeString(http.StatusBadRequest, "The cursor in the query is invalid")
+	}
+
+	condition := "(created_at " + comparison + " ? OR (created_at = ? AND id " + comparison + " ?))"
+	return order, condition, []any{createdAt, createdAt, query.Cursor.ID}, nil
+}
@@ -0,0 +1,226 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLLoginLockoutRepository implements LoginLockoutRepository
+var _ interfaces.LoginLockoutRepository = (*MySQLLoginLockoutRepository)(nil)
+
+type MySQLLoginLockoutRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLLoginLockoutRepository(database interfaces.Database) (interfaces.LoginLockoutRepository, error) {
+	return &MySQLLoginLockoutRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// SelectLoginLockout returns the failed logins of the account or client IP.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// subject not having failed logins.
+func (r MySQLLoginLockoutRepository) SelectLoginLockout(kind string, subject string) (*dtos.LoginLockout, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			kind,
+			subject,
+			failures,
+			last_failure_at,
+			locked_until
+		FROM
+			login_lockouts
+		WHERE
+			kind = ? AND
+			subject = ?;
+	`, kind, subject)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	lockout := new(dtos.LoginLockout)
+
+	err := row.Scan(
+		&lockout.Kind,
+		&lockout.Subject,
+		&lockout.Failures,
+		&lockout.LastFailureAt,
+		&lockout.LockedUntil,
+	)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return lockout, nil
+}
+
+// SelectActiveLoginLockouts returns the accounts and client IPs locked out
+// after now.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r MySQLLoginLockoutRepository) SelectActiveLoginLockouts(now time.Time) ([]*dtos.LoginLockout, *utils.ErrorCode) {
+	rows, err := r.db.Query(`
+		SELECT
+			kind,
+			subject,
+			failures,
+			last_failure_at,
+			locked_until
+		FROM
+			login_lockouts
+		WHERE
+			locked_until > ?
+		ORDER BY
+			locked_until DESC;
+	`, now)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	lockouts := make([]*dtos.LoginLockout, 0)
+
+	for rows.Next() {
+		lockout := new(dtos.LoginLockout)
+
+		err := rows.Scan(
+			&lockout.Kind,
+			&lockout.Subject,
+			&lockout.Failures,
+			&lockout.LastFailureAt,
+			&lockout.LockedUntil,
+		)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		lockouts = append(lockouts, lockout)
+	}
+
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return lockouts, nil
+}
+
+// UpdateLoginLockout applies update to the failed logins of the account or
+// client IP, starting from none at now, then stores them. The row is locked
+// meanwhile, so concurrent failed logins are all counted.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r MySQLLoginLockoutRepository) UpdateLoginLockout(kind string, subject string, now time.Time, update func(lockout *dtos.LoginLockout)) *utils.ErrorCode {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	// Missing rows can't be locked, so one without failures is made first
+	_, err = transaction.Exec(`
+		INSERT IGNORE INTO login_lockouts(kind, subject, failures, last_failure_at, locked_until)
+		VALUES (?, ?, 0, ?, NULL);
+	`, kind, subject, now)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	row := transaction.QueryRow(`
+		SELECT
+			kind,
+			subject,
+			failures,
+			last_failure_at,
+			locked_until
+		FROM
+			login_lockouts
+		WHERE
+			kind = ? AND
+			subject = ?
+		FOR UPDATE;
+	`, kind, subject)
+
+	lockout := new(dtos.LoginLockout)
+
+	err = row.Scan(
+		&lockout.Kind,
+		&lockout.Subject,
+		&lockout.Failures,
+		&lockout.LastFailureAt,
+		&lockout.LockedUntil,
+	)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	update(lockout)
+
+	_, err = transaction.Exec(`
+		UPDATE
+			login_lockouts
+		SET
+			failures = ?,
+			last_failure_at = ?,
+			locked_until = ?
+		WHERE
+			kind = ? AND
+			subject = ?;
+	`, lockout.Failures, lockout.LastFailureAt, lockout.LockedUntil, kind, subject)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RemoveLoginLockout forgets the failed logins of the account or client IP.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// subject not having failed logins.
+func (r MySQLLoginLockoutRepository) RemoveLoginLockout(kind string, subject string) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		DELETE FROM login_lockouts
+		WHERE
+			kind = ? AND
+			subject = ?;
+	`, kind, subject)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, fmt.Sprintf("No failed logins for %s '%s'", kind, subject))
+	}
+
+	return nil
+}
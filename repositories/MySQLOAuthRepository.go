@@ -0,0 +1,290 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLOAuthRepository implements OAuthRepository
+var _ interfaces.OAuthRepository = (*MySQLOAuthRepository)(nil)
+
+type MySQLOAuthRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLOAuthRepository(database interfaces.Database) (interfaces.OAuthRepository, error) {
+	return &MySQLOAuthRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// CreateOAuthClient registers the client.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLOAuthRepository) CreateOAuthClient(client *dtos.OAuthClient) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO oauth_clients(id, name, secret_hash, public, redirect_uris, grant_types, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+	`,
+		client.ID,
+		client.Name,
+		client.SecretHash,
+		client.Public,
+		strings.Join(client.RedirectURIs, " "),
+		strings.Join(client.GrantTypes, " "),
+		strings.Join(client.Scopes, " "),
+		client.CreatedAt,
+	)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectOAuthClient returns the client with the given ID.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// client not being found.
+func (r MySQLOAuthRepository) SelectOAuthClient(clientId string) (*dtos.OAuthClient, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			name,
+			secret_hash,
+			public,
+			redirect_uris,
+			grant_types,
+			scopes,
+			created_at
+		FROM
+			oauth_clients
+		WHERE
+			id = ?;
+	`, clientId)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	client, err := scanOAuthClient(row)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return client, nil
+}
+
+// SelectOAuthClients returns every registered client.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to scan a row.
+func (r MySQLOAuthRepository) SelectOAuthClients() ([]*dtos.OAuthClient, *utils.ErrorCode) {
+	rows, err := r.db.Query(`
+		SELECT
+			id,
+			name,
+			secret_hash,
+			public,
+			redirect_uris,
+			grant_types,
+			scopes,
+			created_at
+		FROM
+			oauth_clients
+		ORDER BY
+			created_at;
+	`)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	clients := make([]*dtos.OAuthClient, 0)
+
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		clients = append(clients, client)
+	}
+
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return clients, nil
+}
+
+// RemoveOAuthClient unregisters the client along with its authorization
+// codes. Tokens already issued to it live until they expire.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// client not being found.
+func (r MySQLOAuthRepository) RemoveOAuthClient(clientId string) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		DELETE FROM oauth_clients
+		WHERE
+			id = ?;
+	`, clientId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, "OAuth client not found")
+	}
+
+	return nil
+}
+
+// CreateAuthorizationCode stores an issued authorization code.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLOAuthRepository) CreateAuthorizationCode(code *dtos.AuthorizationCode) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO oauth_authorization_codes(hash, client_id, user_id, redirect_uri, scopes, code_challenge, nonce, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+	`,
+		code.Hash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		strings.Join(code.Scopes, " "),
+		code.CodeChallenge,
+		code.Nonce,
+		code.ExpiresAt,
+	)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectAuthorizationCodeFromHash returns the authorization code with the
+// given hash, even if expired or used.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// hash not being found.
+func (r MySQLOAuthRepository) SelectAuthorizationCodeFromHash(hash string) (*dtos.AuthorizationCode, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			hash,
+			client_id,
+			user_id,
+			redirect_uri,
+			scopes,
+			code_challenge,
+			nonce,
+			expires_at,
+			used_at
+		FROM
+			oauth_authorization_codes
+		WHERE
+			hash = ?;
+	`, hash)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	code := new(dtos.AuthorizationCode)
+
+	var scopes string
+
+	err := row.Scan(
+		&code.ID,
+		&code.Hash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&scopes,
+		&code.CodeChallenge,
+		&code.Nonce,
+		&code.ExpiresAt,
+		&code.UsedAt,
+	)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	code.Scopes = strings.Fields(scopes)
+
+	return code, nil
+}
+
+// UseAuthorizationCode marks the authorization code as used, returning false
+// when it was already used by someone else.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows.
+func (r MySQLOAuthRepository) UseAuthorizationCode(id int, usedAt time.Time) (bool, *utils.ErrorCode) {
+	result, err := r.db.Exec(`
+		UPDATE
+			oauth_authorization_codes
+		SET
+			used_at = ?
+		WHERE
+			id = ? AND
+			used_at IS NULL;
+	`, usedAt, id)
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func scanOAuthClient(row rowScanner) (*dtos.OAuthClient, error) {
+	client := new(dtos.OAuthClient)
+
+	var redirectURIs, grantTypes, scopes string
+
+	err := row.Scan(
+		&client.ID,
+		&client.Name,
+		&client.SecretHash,
+		&client.Public,
+		&redirectURIs,
+		&grantTypes,
+		&scopes,
+		&client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client.RedirectURIs = strings.Fields(redirectURIs)
+	client.GrantTypes = strings.Fields(grantTypes)
+	client.Scopes = strings.Fields(scopes)
+
+	return client, nil
+}
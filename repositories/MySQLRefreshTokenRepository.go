@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLRefreshTokenRepository implements RefreshTokenRepository
+var _ interfaces.RefreshTokenRepository = (*MySQLRefreshTokenRepository)(nil)
+
+type MySQLRefreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLRefreshTokenRepository(database interfaces.Database) (interfaces.RefreshTokenRepository, error) {
+	return &MySQLRefreshTokenRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// CreateRefreshToken stores a new refresh token.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLRefreshTokenRepository) CreateRefreshToken(token *dtos.RefreshToken) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO refresh_tokens(user_id, family_id, hash, expires_at)
+		VALUES (?, ?, ?, ?);
+	`, token.UserID, token.FamilyID, token.Hash, token.ExpiresAt)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectRefreshTokenFromHash returns the refresh token with the given hash.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// hash not being found.
+func (r MySQLRefreshTokenRepository) SelectRefreshTokenFromHash(hash string) (*dtos.RefreshToken, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			user_id,
+			family_id,
+			hash,
+			expires_at,
+			used_at,
+			revoked_at
+		FROM
+			refresh_tokens
+		WHERE
+			hash = ?;
+	`, hash)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	token := new(dtos.RefreshToken)
+
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.Hash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return token, nil
+}
+
+// UseRefreshToken marks the refresh token as used, returning false when it
+// was already used or revoked by someone else.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows.
+func (r MySQLRefreshTokenRepository) UseRefreshToken(id int, usedAt time.Time) (bool, *utils.ErrorCode) {
+	result, err := r.db.Exec(`
+		UPDATE
+			refresh_tokens
+		SET
+			used_at = ?
+		WHERE
+			id = ? AND
+			used_at IS NULL AND
+			revoked_at IS NULL;
+	`, usedAt, id)
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token sharing familyId.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLRefreshTokenRepository) RevokeRefreshTokenFamily(familyId string, revokedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			refresh_tokens
+		SET
+			revoked_at = ?
+		WHERE
+			family_id = ? AND
+			revoked_at IS NULL;
+	`, revokedAt, familyId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLRefreshTokenRepository) RevokeUserRefreshTokens(userId int, revokedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			refresh_tokens
+		SET
+			revoked_at = ?
+		WHERE
+			user_id = ? AND
+			revoked_at IS NULL;
+	`, revokedAt, userId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
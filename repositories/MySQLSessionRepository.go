@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLSessionRepository implements SessionRepository
+var _ interfaces.SessionRepository = (*MySQLSessionRepository)(nil)
+
+type MySQLSessionRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLSessionRepository(database interfaces.Database) (interfaces.SessionRepository, error) {
+	return &MySQLSessionRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// CreateSession stores a new session.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLSessionRepository) CreateSession(session *dtos.Session) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO sessions(id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?);
+	`, session.ID, session.UserID, session.UserAgent, session.IP, session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectSession returns the session with the given ID, even if expired or
+// revoked.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// session not being found.
+func (r MySQLSessionRepository) SelectSession(id string) (*dtos.Session, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			user_id,
+			user_agent,
+			ip,
+			created_at,
+			last_seen_at,
+			expires_at,
+			revoked_at
+		FROM
+			sessions
+		WHERE
+			id = ?;
+	`, id)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	session := new(dtos.Session)
+
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return session, nil
+}
+
+// SelectUserSessions returns the sessions of the user neither revoked nor
+// expired at now, most recently seen first.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r MySQLSessionRepository) SelectUserSessions(userId int, now time.Time) ([]*dtos.Session, *utils.ErrorCode) {
+	rows, err := r.db.Query(`
+		SELECT
+			id,
+			user_id,
+			user_agent,
+			ip,
+			created_at,
+			last_seen_at,
+			expires_at
+		FROM
+			sessions
+		WHERE
+			user_id = ? AND
+			revoked_at IS NULL AND
+			expires_at > ?
+		ORDER BY
+			last_seen_at DESC;
+	`, userId, now)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*dtos.Session, 0)
+
+	for rows.Next() {
+		session := new(dtos.Session)
+
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return sessions, nil
+}
+
+// TouchSession records the session was seen, extending its expiration.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLSessionRepository) TouchSession(id string, lastSeenAt time.Time, expiresAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			sessions
+		SET
+			last_seen_at = ?,
+			expires_at = ?
+		WHERE
+			id = ?;
+	`, lastSeenAt, expiresAt, id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RevokeSession revokes the session of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// session not being found, belonging to another user or being already revoked.
+func (r MySQLSessionRepository) RevokeSession(userId int, id string, revokedAt time.Time) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		UPDATE
+			sessions
+		SET
+			revoked_at = ?
+		WHERE
+			id = ? AND
+			user_id = ? AND
+			revoked_at IS NULL;
+	`, revokedAt, id, userId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, "Session not found")
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes every session of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLSessionRepository) RevokeUserSessions(userId int, revokedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			sessions
+		SET
+			revoked_at = ?
+		WHERE
+			user_id = ? AND
+			revoked_at IS NULL;
+	`, revokedAt, userId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLTokenRevocationRepository implements TokenRevocationRepository
+var _ interfaces.TokenRevocationRepository = (*MySQLTokenRevocationRepository)(nil)
+
+type MySQLTokenRevocationRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLTokenRevocationRepository(database interfaces.Database) (interfaces.TokenRevocationRepository, error) {
+	return &MySQLTokenRevocationRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// RevokeToken revokes a single token by its id, until it expires.
+//
+// Revocations already expired by now are purged along the way.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// query not being sucessfully executed.
+func (r MySQLTokenRevocationRepository) RevokeToken(tokenId string, expiresAt time.Time, now time.Time) *utils.ErrorCode {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		DELETE FROM
+			revoked_tokens
+		WHERE
+			expires_at < ?;
+	`, now)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		INSERT IGNORE INTO revoked_tokens(token_id, expires_at)
+		VALUES (?, ?);
+	`, tokenId, expiresAt)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes every token of the user issued before
+// issuedBefore.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLTokenRevocationRepository) RevokeUserTokens(userId int, issuedBefore time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO user_token_revocations(user_id, issued_before)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE
+			issued_before = GREATEST(issued_before, VALUES(issued_before));
+	`, userId, issuedBefore)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked checks if the token was revoked by its id or by a
+// revocation of every token of its user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r MySQLTokenRevocationRepository) IsTokenRevoked(tokenId string, userId int, issuedAt time.Time) (bool, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			EXISTS (
+				SELECT
+					1
+				FROM
+					revoked_tokens
+				WHERE
+					token_id = ?
+			) OR EXISTS (
+				SELECT
+					1
+				FROM
+					user_token_revocations
+				WHERE
+					user_id = ? AND
+					issued_before > ?
+			);
+	`, tokenId, userId, issuedAt)
+	if row.Err() != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	var revoked bool
+
+	err := row.Scan(&revoked)
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return revoked, nil
+}
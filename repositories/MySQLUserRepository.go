@@ -4,9 +4,13 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/d1360-64rc14/simple-api/config"
 	"github.com/d1360-64rc14/simple-api/dtos"
 	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
 	"github.com/d1360-64rc14/simple-api/utils"
 )
 
@@ -15,48 +19,24 @@ var _ interfaces.UserRepository = (*MySQLUserRepository)(nil)
 
 type MySQLUserRepository struct {
 	db *sql.DB
+	// fullText tells if the driver supports FULLTEXT indexes, RamMySQL
+	// doesn't
+	fullText bool
 }
 
 func NewMySQLUserRepository(database interfaces.Database) (interfaces.UserRepository, error) {
-	repo := &MySQLUserRepository{
-		db: database.DB(),
-	}
-
-	err := repo.createUserTableIfNotExist()
-	if err != nil {
-		return nil, err
-	}
-
-	return repo, nil
+	return &MySQLUserRepository{
+		db:       database.DB(),
+		fullText: database.Driver() != config.DriverRamSQL,
+	}, nil
 }
 
 func (r MySQLUserRepository) Close() error {
 	return r.db.Close()
 }
 
-func (r MySQLUserRepository) createUserTableIfNotExist() error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Commit()
-
-	_, err = tx.Exec(`
-		CREATE TABLE IF NOT EXISTS users(
-			id       INTEGER      NOT NULL PRIMARY KEY AUTO_INCREMENT,
-			username VARCHAR(50)  NOT NULL,
-			email    VARCHAR(100) NOT NULL UNIQUE,
-			hash     CHAR(72)     NOT NULL
-		);
-	`)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// CreateUser adds a new user to the database, returning an identified user.
+// CreateUser adds a new user to the database with the models.RoleUser role,
+// returning an identified user.
 //
 // Errors can be caused by:
 // transaction not being started;
@@ -74,7 +54,11 @@ func (r MySQLUserRepository) CreateUser(user *dtos.UserWithHash) (*dtos.Identifi
 		VALUES (?, ?, ?);
 	`, user.UserName, user.Email, user.Hash)
 	if err != nil {
-		return nil, utils.NewErrorCodeString(http.StatusConflict, "Email address already exist")
+		transaction.Rollback()
+		if isDuplicateEntry(err) {
+			return nil, utils.NewErrorCodeString(http.StatusConflict, "Email address already exist")
+		}
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
 	}
 
 	row := transaction.QueryRow(`
@@ -91,6 +75,16 @@ func (r MySQLUserRepository) CreateUser(user *dtos.UserWithHash) (*dtos.Identifi
 	var id int
 	err = row.Scan(&id)
 	if err != nil {
+		transaction.Rollback()
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		INSERT INTO user_roles(user_id, role)
+		VALUES (?, ?);
+	`, id, models.RoleUser)
+	if err != nil {
+		transaction.Rollback()
 		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
 	}
 
@@ -114,7 +108,9 @@ func (r MySQLUserRepository) SelectUserFromId(id int) (*dtos.IdentifiedUser, *ut
 		SELECT
 			id,
 			username,
-			email
+			email,
+			verified_at IS NOT NULL,
+			last_login_at
 		FROM
 			users
 		WHERE
@@ -127,7 +123,7 @@ func (r MySQLUserRepository) SelectUserFromId(id int) (*dtos.IdentifiedUser, *ut
 
 	user := new(dtos.IdentifiedUser)
 
-	err := row.Scan(&user.ID, &user.UserName, &user.Email)
+	err := row.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt)
 	if err != nil {
 		return nil, utils.NewErrorCode(http.StatusNotFound, err)
 	}
@@ -145,7 +141,9 @@ func (r MySQLUserRepository) SelectUserFromEmail(email string) (*dtos.Identified
 		SELECT
 			id,
 			email,
-			username
+			username,
+			verified_at IS NOT NULL,
+			last_login_at
 		FROM
 			users
 		WHERE
@@ -158,7 +156,7 @@ func (r MySQLUserRepository) SelectUserFromEmail(email string) (*dtos.Identified
 
 	user := new(dtos.IdentifiedUser)
 
-	err := row.Scan(&user.ID, &user.Email, &user.UserName)
+	err := row.Scan(&user.ID, &user.Email, &user.UserName, &user.EmailVerified, &user.LastLoginAt)
 	if err != nil {
 		return nil, utils.NewErrorCode(http.StatusNotFound, err)
 	}
@@ -174,7 +172,7 @@ func (r MySQLUserRepository) SelectUserFromEmail(email string) (*dtos.Identified
 func (r MySQLUserRepository) SelectUserHashFromId(id int) (string, *utils.ErrorCode) {
 	row := r.db.QueryRow(`
 		SELECT
-			hash,
+			hash
 		FROM
 			users
 		WHERE
@@ -206,6 +204,8 @@ func (r MySQLUserRepository) SelectCompleteUserFromId(id int) (*dtos.IdentifiedU
 			id,
 			username,
 			email,
+			verified_at IS NOT NULL,
+			last_login_at,
 			hash
 		FROM
 			users
@@ -219,7 +219,7 @@ func (r MySQLUserRepository) SelectCompleteUserFromId(id int) (*dtos.IdentifiedU
 
 	user := new(dtos.IdentifiedUserWithHash)
 
-	err := row.Scan(&user.ID, &user.UserName, &user.Email, &user.Hash)
+	err := row.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt, &user.Hash)
 	if err != nil {
 		return nil, utils.NewErrorCode(http.StatusNotFound, err)
 	}
@@ -227,64 +227,286 @@ func (r MySQLUserRepository) SelectCompleteUserFromId(id int) (*dtos.IdentifiedU
 	return user, nil
 }
 
-// SelectAllUsers returns a list of all users from database.
+// SelectAllUsers returns every user from database, by id.
 //
 // Errors can be caused by:
 // user count query not being successfully executed;
-// no rows being found at user count;
-// row being read wrongly;
-// no content being found at some row.
+// user query not being successfully executed;
+// row being read wrongly.
 func (r MySQLUserRepository) SelectAllUsers() ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
+	return selectAllUsers(r)
+}
+
+// SelectUsers returns the page of users selected by query, along with the
+// total count of users matching its filters.
+//
+// Users after a backward cursor are returned from the closest to the
+// farthest, in the reverse order of the sort.
+//
+// Errors can be caused by:
+// sort not being known;
+// user count query not being successfully executed;
+// user query not being successfully executed;
+// row being read wrongly.
+func (r MySQLUserRepository) SelectUsers(query *dtos.UserQuery) ([]*dtos.IdentifiedUser, int, *utils.ErrorCode) {
+	field := strings.TrimPrefix(query.Sort, "-")
+	if field != "id" && field != "username" {
+		return nil, 0, utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Unknown sort '%s'", query.Sort))
+	}
+
+	where, args := userQueryFilter(query, "LIKE")
+
 	row := r.db.QueryRow(`
 		SELECT
-			count(id)
-		FROM users;
-	`)
+			count(*)
+		FROM
+			users
+		`+where+`;
+	`, args...)
 
 	if row.Err() != nil {
-		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
 	}
 
-	var userCount int
+	var total int
 
-	err := row.Scan(&userCount)
+	err := row.Scan(&total)
 	if err != nil {
-		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	order, keysetCondition, keysetArgs := userKeyset(query)
+	if keysetCondition != "" {
+		if where == "" {
+			where = "WHERE " + keysetCondition
+		} else {
+			where += " AND " + keysetCondition
+		}
+		args = append(args, keysetArgs...)
 	}
 
 	rows, err := r.db.Query(`
 		SELECT
 			id,
 			username,
-			email
+			email,
+			verified_at IS NOT NULL,
+			last_login_at
 		FROM
-			users;
-	`) // TODO: Add pagination
+			users
+		`+where+`
+		ORDER BY
+			`+order+`
+		LIMIT ? OFFSET ?;
+	`, append(args, query.Limit, query.Offset)...)
 	if err != nil {
-		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
 	}
 	defer rows.Close()
 
-	users := make([]*dtos.IdentifiedUser, 0, userCount)
+	users := make([]*dtos.IdentifiedUser, 0, query.Limit)
 
 	for rows.Next() {
 		user := new(dtos.IdentifiedUser)
 
-		if rows.Err() != nil {
-			return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+		err := rows.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt)
+		if err != nil {
+			return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		users = append(users, user)
+	}
+
+	if rows.Err() != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return users, total, nil
+}
+
+// SearchUsers returns at most query.Limit users whose username or email have
+// words starting with every term of query, the most relevant first.
+//
+// Without FULLTEXT support, users having every term anywhere in their
+// username or email are returned by username instead.
+//
+// Errors can be caused by:
+// query not being successfully executed;
+// row being read wrongly.
+func (r MySQLUserRepository) SearchUsers(query *dtos.UserSearchQuery) ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
+	terms := query.Terms()
+
+	var rows *sql.Rows
+	var err error
+
+	if r.fullText {
+		// Boolean mode "+diego* +mail*" requires a word starting with each term
+		against := make([]string, len(terms))
+		for i, term := range terms {
+			against[i] = "+" + term + "*"
 		}
 
-		err := rows.Scan(&user.ID, &user.UserName, &user.Email)
+		rows, err = r.db.Query(`
+			SELECT
+				id,
+				username,
+				email,
+				verified_at IS NOT NULL,
+				last_login_at
+			FROM
+				users
+			WHERE
+				MATCH (username, email) AGAINST (? IN BOOLEAN MODE)
+			ORDER BY
+				MATCH (username, email) AGAINST (? IN BOOLEAN MODE) DESC,
+				username ASC,
+				id ASC
+			LIMIT ?;
+		`, strings.Join(against, " "), strings.Join(against, " "), query.Limit)
+	} else {
+		conditions := make([]string, len(terms))
+		args := make([]any, 0, 2*len(terms)+1)
+		for i, term := range terms {
+			conditions[i] = "(username LIKE ? OR email LIKE ?)"
+			pattern := "%" + escapeLike(term) + "%"
+			args = append(args, pattern, pattern)
+		}
+
+		rows, err = r.db.Query(`
+			SELECT
+				id,
+				username,
+				email,
+				verified_at IS NOT NULL,
+				last_login_at
+			FROM
+				users
+			WHERE
+				`+strings.Join(conditions, " AND ")+`
+			ORDER BY
+				username ASC,
+				id ASC
+			LIMIT ?;
+		`, append(args, query.Limit)...)
+	}
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	users := make([]*dtos.IdentifiedUser, 0, query.Limit)
+
+	for rows.Next() {
+		user := new(dtos.IdentifiedUser)
+
+		err := rows.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt)
 		if err != nil {
-			return nil, utils.NewErrorCode(http.StatusNotFound, err)
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
 		}
 
 		users = append(users, user)
 	}
 
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return users, nil
+}
+
+// allUsersPageSize is how many users selectAllUsers reads at once.
+const allUsersPageSize = 100
+
+// selectAllUsers reads every user of repo, by id, a page at a time.
+func selectAllUsers(repo interfaces.UserRepository) ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
+	query := &dtos.UserQuery{Limit: allUsersPageSize, Sort: "id"}
+
+	users, total, errC := repo.SelectUsers(query)
+	if errC != nil {
+		return nil, errC
+	}
+
+	for len(users) < total {
+		query.Offset += allUsersPageSize
+
+		page, _, errC := repo.SelectUsers(query)
+		if errC != nil {
+			return nil, errC
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		users = append(users, page...)
+	}
+
 	return users, nil
 }
 
+// userQueryFilter returns the WHERE clause of the filters of query, empty
+// when there are none, along with its arguments. like is the operator
+// matching patterns without regard to case, which differs between drivers.
+func userQueryFilter(query *dtos.UserQuery, like string) (string, []any) {
+	conditions := make([]string, 0, 2)
+	args := make([]any, 0, 2)
+
+	if query.EmailDomain != "" {
+		conditions = append(conditions, "email "+like+" ?")
+		args = append(args, "%@"+escapeLike(query.EmailDomain))
+	}
+	if query.UsernamePrefix != "" {
+		conditions = append(conditions, "username "+like+" ?")
+		args = append(args, escapeLike(query.UsernamePrefix)+"%")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// userKeyset returns the ORDER BY clause of the sort of query, tie-broken by
+// id so pages never overlap, and the condition selecting the users past its
+// cursor, empty when there is none, along with its arguments.
+func userKeyset(query *dtos.UserQuery) (string, string, []any) {
+	field := strings.TrimPrefix(query.Sort, "-")
+	descending := strings.HasPrefix(query.Sort, "-")
+
+	if query.Cursor != nil && query.Cursor.Backward {
+		descending = !descending
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	order := "id " + direction
+	if field == "username" {
+		order = "username " + direction + ", " + order
+	}
+
+	if query.Cursor == nil {
+		return order, "", nil
+	}
+
+	if field == "username" {
+		condition := "(username " + comparison + " ? OR (username = ? AND id " + comparison + " ?))"
+		return order, condition, []any{query.Cursor.Key, query.Cursor.Key, query.Cursor.ID}
+	}
+
+	return order, "id " + comparison + " ?", []any{query.Cursor.ID}
+}
+
+// escapeLike escapes the wildcards of value, so it matches literally in a
+// LIKE pattern.
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // RemoveUser removes an user from the database.
 //
 // Will rollback if more than one user get removed.
@@ -383,8 +605,9 @@ func (r MySQLUserRepository) UpdateUsername(id int, newUsername string) *utils.E
 			username = ?
 		WHERE
 			id = ?;
-	`, id, newUsername)
+	`, newUsername, id)
 	if err != nil {
+		transaction.Rollback()
 		return utils.NewErrorCode(http.StatusBadRequest, err)
 	}
 
@@ -408,3 +631,203 @@ func (r MySQLUserRepository) UpdateUsername(id int, newUsername string) *utils.E
 
 	return nil
 }
+
+// SelectUserRoles returns the roles of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r MySQLUserRepository) SelectUserRoles(id int) ([]string, *utils.ErrorCode) {
+	rows, err := r.db.Query(`
+		SELECT
+			role
+		FROM
+			user_roles
+		WHERE
+			user_id = ?
+		ORDER BY
+			role;
+	`, id)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	roles := make([]string, 0, 1)
+
+	for rows.Next() {
+		var role string
+
+		err := rows.Scan(&role)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		roles = append(roles, role)
+	}
+
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return roles, nil
+}
+
+// AddUserRole grants a role to the user, doing nothing if they already have it.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLUserRepository) AddUserRole(id int, role string) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT IGNORE INTO user_roles(user_id, role)
+		VALUES (?, ?);
+	`, id, role)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RemoveUserRole revokes a role from the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// user not having the role.
+func (r MySQLUserRepository) RemoveUserRole(id int, role string) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		DELETE FROM
+			user_roles
+		WHERE
+			user_id = ? AND
+			role = ?;
+	`, id, role)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(
+			http.StatusNotFound,
+			fmt.Sprintf("User ID %d doesn't have the role '%s'", id, role),
+		)
+	}
+
+	return nil
+}
+
+// UpdateUserHash changes the password hash for the given id.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// id not being found.
+func (r MySQLUserRepository) UpdateUserHash(id int, newHash string) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			hash = ?
+		WHERE
+			id = ?;
+	`, newHash, id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, fmt.Sprintf("User ID %d doesn't exist", id))
+	}
+
+	return nil
+}
+
+// VerifyUserEmail marks the user email address as verified, keeping the
+// first verification time of already verified users.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLUserRepository) VerifyUserEmail(id int, verifiedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			verified_at = ?
+		WHERE
+			id = ? AND
+			verified_at IS NULL;
+	`, verifiedAt, id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// UpdateUserEmail changes the user email address, marking it as verified
+// since the user confirmed owning it.
+//
+// Errors can be caused by:
+// email address already being used;
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// id not being found.
+func (r MySQLUserRepository) UpdateUserEmail(id int, newEmail string, verifiedAt time.Time) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			email = ?,
+			verified_at = ?
+		WHERE
+			id = ?;
+	`, newEmail, verifiedAt, id)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return utils.NewErrorCodeString(http.StatusConflict, "Email address already exist")
+		}
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, fmt.Sprintf("User ID %d doesn't exist", id))
+	}
+
+	return nil
+}
+
+// UpdateUserLastLogin records when the user last logged in.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLUserRepository) UpdateUserLastLogin(id int, lastLoginAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			last_login_at = ?
+		WHERE
+			id = ?;
+	`, lastLoginAt, id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+)
+
+func TestSelectAllUsers(t *testing.T) {
+	testCases := []int{0, 1, allUsersPageSize, 2*allUsersPageSize + 50}
+
+	for i, userCount := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			userRepo := mocks.NewMockedUserRepository()
+			for j := 0; j < userCount; j++ {
+				userRepo.CreateUser(&dtos.UserWithHash{
+					UserModel: models.UserModel{UserName: fmt.Sprintf("user%d", j), Email: fmt.Sprintf("user%d@mail.com", j)},
+					Hash:      "fb78ed1e-a121-542f-a68d-fcd21ffe83c5",
+				})
+			}
+
+			users, err := selectAllUsers(userRepo)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(users) != userCount {
+				t.Fatalf("Every one of the %d users should be selected, got %d", userCount, len(users))
+			}
+			for j := 1; j < len(users); j++ {
+				if users[j-1].ID >= users[j].ID {
+					t.Fatalf("Users should be ordered by id, got '%d' before '%d'", users[j-1].ID, users[j].ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// MySQLUserTokenRepository implements UserTokenRepository
+var _ interfaces.UserTokenRepository = (*MySQLUserTokenRepository)(nil)
+
+type MySQLUserTokenRepository struct {
+	db *sql.DB
+}
+
+func NewMySQLUserTokenRepository(database interfaces.Database) (interfaces.UserTokenRepository, error) {
+	return &MySQLUserTokenRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// CreateUserToken stores a new user token.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLUserTokenRepository) CreateUserToken(token *dtos.UserToken) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO user_tokens(user_id, purpose, hash, payload, expires_at)
+		VALUES (?, ?, ?, ?, ?);
+	`, token.UserID, token.Purpose, token.Hash, token.Payload, token.ExpiresAt)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectUserTokenFromHash returns the user token with the given purpose and
+// hash.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// hash not being found.
+func (r MySQLUserTokenRepository) SelectUserTokenFromHash(purpose string, hash string) (*dtos.UserToken, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			user_id,
+			purpose,
+			hash,
+			payload,
+			expires_at,
+			used_at
+		FROM
+			user_tokens
+		WHERE
+			purpose = ? AND
+			hash = ?;
+	`, purpose, hash)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	token := new(dtos.UserToken)
+
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.Hash,
+		&token.Payload,
+		&token.ExpiresAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return token, nil
+}
+
+// UseUserToken marks the user token as used, returning false when it was
+// already used.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows.
+func (r MySQLUserTokenRepository) UseUserToken(id int, usedAt time.Time) (bool, *utils.ErrorCode) {
+	result, err := r.db.Exec(`
+		UPDATE
+			user_tokens
+		SET
+			used_at = ?
+		WHERE
+			id = ? AND
+			used_at IS NULL;
+	`, usedAt, id)
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// InvalidateUserTokens marks every unused token of the user with the given
+// purpose as used.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r MySQLUserTokenRepository) InvalidateUserTokens(userId int, purpose string, invalidatedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			user_tokens
+		SET
+			used_at = ?
+		WHERE
+			user_id = ? AND
+			purpose = ? AND
+			used_at IS NULL;
+	`, invalidatedAt, userId, purpose)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// postgresUniqueViolation is the PostgreSQL error code of UNIQUE and PRIMARY
+// KEY violations.
+const postgresUniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == postgresUniqueViolation
+}
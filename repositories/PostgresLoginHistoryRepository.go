@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/database"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// PostgresLoginHistoryRepository implements LoginHistoryRepository
+var _ interfaces.LoginHistoryRepository = (*PostgresLoginHistoryRepository)(nil)
+
+type PostgresLoginHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresLoginHistoryRepository(database interfaces.Database) (interfaces.LoginHistoryRepository, error) {
+	return &PostgresLoginHistoryRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// CreateLoginAttempt appends the attempt to the login history.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r PostgresLoginHistoryRepository) CreateLoginAttempt(attempt *dtos.LoginAttempt) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO login_history(user_id, email, ip, user_agent, succeeded, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`, attempt.UserID, attempt.Email, attempt.IP, attempt.UserAgent, attempt.Succeeded, attempt.Reason, attempt.CreatedAt)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectUserLoginAttempts returns the attempts of the user selected by
+// query, newest first, along with their total count.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r PostgresLoginHistoryRepository) SelectUserLoginAttempts(userId int, query *dtos.LoginHistoryQuery) ([]*dtos.LoginAttempt, int, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			count(*)
+		FROM
+			login_history
+		WHERE
+			user_id = $1;
+	`, userId)
+
+	if row.Err() != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	var total int
+
+	err := row.Scan(&total)
+	if err != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	order, keysetCondition, keysetArgs, errC := loginHistoryKeyset(query)
+	if errC != nil {
+		return nil, 0, errC
+	}
+
+	where := "user_id = ?"
+	args := []any{userId}
+	if keysetCondition != "" {
+		where += " AND " + keysetCondition
+		args = append(args, keysetArgs...)
+	}
+
+	rows, err := r.db.Query(database.Rebind(`
+		SELECT
+			id,
+			user_id,
+			email,
+			ip,
+			user_agent,
+			succeeded,
+			reason,
+			created_at
+		FROM
+			login_history
+		WHERE
+			`+where+`
+		ORDER BY
+			`+order+`
+		LIMIT ? OFFSET ?;
+	`), append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	attempts := make([]*dtos.LoginAttempt, 0, query.Limit)
+
+	for rows.Next() {
+		attempt := new(dtos.LoginAttempt)
+
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.UserID,
+			&attempt.Email,
+			&attempt.IP,
+			&attempt.UserAgent,
+			&attempt.Succeeded,
+			&attempt.Reason,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	if rows.Err() != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return attempts, total, nil
+}
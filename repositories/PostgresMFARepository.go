@@ -0,0 +1,234 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// PostgresMFARepository implements MFARepository
+var _ interfaces.MFARepository = (*PostgresMFARepository)(nil)
+
+type PostgresMFARepository struct {
+	db *sql.DB
+}
+
+func NewPostgresMFARepository(database interfaces.Database) (interfaces.MFARepository, error) {
+	return &PostgresMFARepository{
+		db: database.DB(),
+	}, nil
+}
+
+// SelectUserTOTP returns the TOTP enrolment of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// user not having a TOTP enrolment.
+func (r PostgresMFARepository) SelectUserTOTP(userId int) (*dtos.UserTOTP, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			user_id,
+			secret,
+			confirmed_at,
+			last_used_step
+		FROM
+			user_totp
+		WHERE
+			user_id = $1;
+	`, userId)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	totp := new(dtos.UserTOTP)
+
+	err := row.Scan(&totp.UserID, &totp.Secret, &totp.ConfirmedAt, &totp.LastUsedStep)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return totp, nil
+}
+
+// SaveUserTOTP starts a new unconfirmed TOTP enrolment, replacing the
+// previous one.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r PostgresMFARepository) SaveUserTOTP(userId int, secret string) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO user_totp(user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			confirmed_at = NULL,
+			last_used_step = 0;
+	`, userId, secret)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// ConfirmUserTOTP enables the TOTP enrolment of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r PostgresMFARepository) ConfirmUserTOTP(userId int, confirmedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			user_totp
+		SET
+			confirmed_at = $1
+		WHERE
+			user_id = $2;
+	`, confirmedAt, userId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records step as the last used one, returning false when a code
+// of the same or a later step was already used.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows.
+func (r PostgresMFARepository) UseTOTPStep(userId int, step int64) (bool, *utils.ErrorCode) {
+	result, err := r.db.Exec(`
+		UPDATE
+			user_totp
+		SET
+			last_used_step = $1
+		WHERE
+			user_id = $2 AND
+			last_used_step < $3;
+	`, step, userId, step)
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// RemoveUserTOTP removes the TOTP enrolment and the recovery codes of the
+// user.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// query not being sucessfully executed.
+func (r PostgresMFARepository) RemoveUserTOTP(userId int) *utils.ErrorCode {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		DELETE FROM user_totp
+		WHERE user_id = $1;
+	`, userId)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		DELETE FROM user_recovery_codes
+		WHERE user_id = $1;
+	`, userId)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of the user with the
+// given hashed ones.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// query not being sucessfully executed.
+func (r PostgresMFARepository) ReplaceRecoveryCodes(userId int, hashes []string) *utils.ErrorCode {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		DELETE FROM user_recovery_codes
+		WHERE user_id = $1;
+	`, userId)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	for _, hash := range hashes {
+		_, err = transaction.Exec(`
+			INSERT INTO user_recovery_codes(user_id, hash)
+			VALUES ($1, $2);
+		`, userId, hash)
+		if err != nil {
+			transaction.Rollback()
+			return utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the recovery code as used, returning false when it
+// doesn't exist or was already used.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows.
+func (r PostgresMFARepository) UseRecoveryCode(userId int, hash string, usedAt time.Time) (bool, *utils.ErrorCode) {
+	result, err := r.db.Exec(`
+		UPDATE
+			user_recovery_codes
+		SET
+			used_at = $1
+		WHERE
+			user_id = $2 AND
+			hash = $3 AND
+			used_at IS NULL;
+	`, usedAt, userId, hash)
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// PostgresTokenRevocationRepository implements TokenRevocationRepository
+var _ interfaces.TokenRevocationRepository = (*PostgresTokenRevocationRepository)(nil)
+
+type PostgresTokenRevocationRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTokenRevocationRepository(database interfaces.Database) (interfaces.TokenRevocationRepository, error) {
+	return &PostgresTokenRevocationRepository{
+		db: database.DB(),
+	}, nil
+}
+
+// RevokeToken revokes a single token by its id, until it expires.
+//
+// Revocations already expired by now are purged along the way.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// query not being sucessfully executed.
+func (r PostgresTokenRevocationRepository) RevokeToken(tokenId string, expiresAt time.Time, now time.Time) *utils.ErrorCode {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		DELETE FROM
+			revoked_tokens
+		WHERE
+			expires_at < $1;
+	`, now)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		INSERT INTO revoked_tokens(token_id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;
+	`, tokenId, expiresAt)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes every token of the user issued before
+// issuedBefore.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r PostgresTokenRevocationRepository) RevokeUserTokens(userId int, issuedBefore time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO user_token_revocations(user_id, issued_before)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			issued_before = GREATEST(user_token_revocations.issued_before, EXCLUDED.issued_before);
+	`, userId, issuedBefore)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked checks if the token was revoked by its id or by a
+// revocation of every token of its user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r PostgresTokenRevocationRepository) IsTokenRevoked(tokenId string, userId int, issuedAt time.Time) (bool, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			EXISTS (
+				SELECT
+					1
+				FROM
+					revoked_tokens
+				WHERE
+					token_id = $1
+			) OR EXISTS (
+				SELECT
+					1
+				FROM
+					user_token_revocations
+				WHERE
+					user_id = $2 AND
+					issued_before > $3
+			);
+	`, tokenId, userId, issuedAt)
+	if row.Err() != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	var revoked bool
+
+	err := row.Scan(&revoked)
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return revoked, nil
+}
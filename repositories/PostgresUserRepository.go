@@ -0,0 +1,695 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/database"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// PostgresUserRepository implements UserRepository
+var _ interfaces.UserRepository = (*PostgresUserRepository)(nil)
+
+// postgresUserSearchVector is the text searched by SearchUsers. It must stay
+// the same as the expression of the users_search index. Symbols are turned
+// into spaces so emails are split into words, as MySQL does.
+const postgresUserSearchVector = `to_tsvector('simple', regexp_replace(username || ' ' || email, '[^[:alnum:]]+', ' ', 'g'))`
+
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUserRepository(db interfaces.Database) (interfaces.UserRepository, error) {
+	return &PostgresUserRepository{db: db.DB()}, nil
+}
+
+func (r PostgresUserRepository) Close() error {
+	return r.db.Close()
+}
+
+// CreateUser adds a new user to the database with the models.RoleUser role,
+// returning an identified user.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// email address already being used;
+// query not being sucessfully executed.
+func (r PostgresUserRepository) CreateUser(user *dtos.UserWithHash) (*dtos.IdentifiedUser, *utils.ErrorCode) {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	row := transaction.QueryRow(`
+		INSERT INTO users(username, email, hash)
+		VALUES ($1, $2, $3)
+		RETURNING id;
+	`, user.UserName, user.Email, user.Hash)
+
+	var id int
+	err = row.Scan(&id)
+	if err != nil {
+		transaction.Rollback()
+		if isUniqueViolation(err) {
+			return nil, utils.NewErrorCodeString(http.StatusConflict, "Email address already exist")
+		}
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	_, err = transaction.Exec(`
+		INSERT INTO user_roles(user_id, role)
+		VALUES ($1, $2);
+	`, id, models.RoleUser)
+	if err != nil {
+		transaction.Rollback()
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return &dtos.IdentifiedUser{
+		ID:        id,
+		UserModel: user.UserModel,
+	}, nil
+}
+
+// SelectUserFromId returns the user with their id.
+//
+// Errors can be caused by:
+// id not being found.
+func (r PostgresUserRepository) SelectUserFromId(id int) (*dtos.IdentifiedUser, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			username,
+			email,
+			verified_at IS NOT NULL,
+			last_login_at
+		FROM
+			users
+		WHERE
+			id = $1;
+	`, id)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	user := new(dtos.IdentifiedUser)
+
+	err := row.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return user, nil
+}
+
+// SelectUserFromEmail returns the identified user from their email, whatever
+// its case, as MySQL compares them.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// no rows being found.
+func (r PostgresUserRepository) SelectUserFromEmail(email string) (*dtos.IdentifiedUser, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			email,
+			username,
+			verified_at IS NOT NULL,
+			last_login_at
+		FROM
+			users
+		WHERE
+			lower(email) = lower($1);
+	`, email)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	user := new(dtos.IdentifiedUser)
+
+	err := row.Scan(&user.ID, &user.Email, &user.UserName, &user.EmailVerified, &user.LastLoginAt)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return user, nil
+}
+
+// SelectUserHashFromId returns the user password hash from database.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// id not being found.
+func (r PostgresUserRepository) SelectUserHashFromId(id int) (string, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			hash
+		FROM
+			users
+		WHERE
+			id = $1;
+	`, id)
+
+	if row.Err() != nil {
+		return "", utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	var hash string
+
+	err := row.Scan(&hash)
+	if err != nil {
+		return "", utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return hash, nil
+}
+
+// SelectCompleteUserFromId returns all user info from database.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// id not being found.
+func (r PostgresUserRepository) SelectCompleteUserFromId(id int) (*dtos.IdentifiedUserWithHash, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT
+			id,
+			username,
+			email,
+			verified_at IS NOT NULL,
+			last_login_at,
+			hash
+		FROM
+			users
+		WHERE
+			id = $1;
+	`, id)
+
+	if row.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	user := new(dtos.IdentifiedUserWithHash)
+
+	err := row.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt, &user.Hash)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusNotFound, err)
+	}
+
+	return user, nil
+}
+
+// SelectAllUsers returns every user from database, by id.
+//
+// Errors can be caused by:
+// user count query not being successfully executed;
+// user query not being successfully executed;
+// row being read wrongly.
+func (r PostgresUserRepository) SelectAllUsers() ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
+	return selectAllUsers(r)
+}
+
+// SelectUsers returns the page of users selected by query, along with the
+// total count of users matching its filters.
+//
+// Users after a backward cursor are returned from the closest to the
+// farthest, in the reverse order of the sort.
+//
+// Errors can be caused by:
+// sort not being known;
+// user count query not being successfully executed;
+// user query not being successfully executed;
+// row being read wrongly.
+func (r PostgresUserRepository) SelectUsers(query *dtos.UserQuery) ([]*dtos.IdentifiedUser, int, *utils.ErrorCode) {
+	field := strings.TrimPrefix(query.Sort, "-")
+	if field != "id" && field != "username" {
+		return nil, 0, utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Unknown sort '%s'", query.Sort))
+	}
+
+	where, args := userQueryFilter(query, "ILIKE")
+
+	row := r.db.QueryRow(database.Rebind(`
+		SELECT
+			count(*)
+		FROM
+			users
+		`+where+`;
+	`), args...)
+
+	if row.Err() != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	var total int
+
+	err := row.Scan(&total)
+	if err != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	order, keysetCondition, keysetArgs := userKeyset(query)
+	if keysetCondition != "" {
+		if where == "" {
+			where = "WHERE " + keysetCondition
+		} else {
+			where += " AND " + keysetCondition
+		}
+		args = append(args, keysetArgs...)
+	}
+
+	rows, err := r.db.Query(database.Rebind(`
+		SELECT
+			id,
+			username,
+			email,
+			verified_at IS NOT NULL,
+			last_login_at
+		FROM
+			users
+		`+where+`
+		ORDER BY
+			`+order+`
+		LIMIT ? OFFSET ?;
+	`), append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	users := make([]*dtos.IdentifiedUser, 0, query.Limit)
+
+	for rows.Next() {
+		user := new(dtos.IdentifiedUser)
+
+		err := rows.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt)
+		if err != nil {
+			return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		users = append(users, user)
+	}
+
+	if rows.Err() != nil {
+		return nil, 0, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return users, total, nil
+}
+
+// SearchUsers returns at most query.Limit users whose username or email have
+// words starting with every term of query, the most relevant first.
+//
+// Errors can be caused by:
+// query not being successfully executed;
+// row being read wrongly.
+func (r PostgresUserRepository) SearchUsers(query *dtos.UserSearchQuery) ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
+	// "diego:* & mail:*" requires a word starting with each term
+	prefixes := make([]string, 0, len(query.Terms()))
+	for _, term := range query.Terms() {
+		prefixes = append(prefixes, term+":*")
+	}
+
+	rows, err := r.db.Query(`
+		SELECT
+			id,
+			username,
+			email,
+			verified_at IS NOT NULL,
+			last_login_at
+		FROM
+			users
+		WHERE
+			`+postgresUserSearchVector+` @@ to_tsquery('simple', $1)
+		ORDER BY
+			ts_rank(`+postgresUserSearchVector+`, to_tsquery('simple', $1)) DESC,
+			username ASC,
+			id ASC
+		LIMIT $2;
+	`, strings.Join(prefixes, " & "), query.Limit)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	users := make([]*dtos.IdentifiedUser, 0, query.Limit)
+
+	for rows.Next() {
+		user := new(dtos.IdentifiedUser)
+
+		err := rows.Scan(&user.ID, &user.UserName, &user.Email, &user.EmailVerified, &user.LastLoginAt)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		users = append(users, user)
+	}
+
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return users, nil
+}
+
+// RemoveUser removes an user from the database.
+//
+// Will rollback if more than one user get removed.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// more than 1 user being found;
+// fail to get number of affected rows.
+func (r PostgresUserRepository) RemoveUser(id int) *utils.ErrorCode {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	result, err := transaction.Exec(`
+		DELETE FROM
+			users
+		WHERE
+			id = $1;
+	`, id)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusBadRequest, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected > 1 {
+		transaction.Rollback()
+		return utils.NewErrorCodeString(
+			http.StatusConflict,
+			fmt.Sprintf("There was %d users with id %d. User not removed.", rowsAffected, id),
+		)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// UserExist checks if an user with given id is present in the database.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// no rows being found.
+func (r PostgresUserRepository) UserExist(id int) (bool, *utils.ErrorCode) {
+	row := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT
+				1
+			FROM
+				users
+			WHERE
+				id = $1
+		);
+	`, id)
+	if row.Err() != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, row.Err())
+	}
+
+	var userExist bool
+
+	err := row.Scan(&userExist)
+
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return userExist, nil
+}
+
+// UpdateUsername changes the username for the given id.
+//
+// Will rollback if more than one user get updated.
+//
+// Errors can be caused by:
+// transaction not being started;
+// transaction not being commited;
+// query not being sucessfully executed.
+func (r PostgresUserRepository) UpdateUsername(id int, newUsername string) *utils.ErrorCode {
+	transaction, err := r.db.Begin()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	result, err := transaction.Exec(`
+		UPDATE
+			users
+		SET
+			username = $1
+		WHERE
+			id = $2;
+	`, newUsername, id)
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusBadRequest, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		transaction.Rollback()
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected > 1 {
+		transaction.Rollback()
+		return utils.NewErrorCodeString(
+			http.StatusConflict,
+			fmt.Sprintf("There was %d users with id %d. User not updated.", rowsAffected, id),
+		)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// SelectUserRoles returns the roles of the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// row being read wrongly.
+func (r PostgresUserRepository) SelectUserRoles(id int) ([]string, *utils.ErrorCode) {
+	rows, err := r.db.Query(`
+		SELECT
+			role
+		FROM
+			user_roles
+		WHERE
+			user_id = $1
+		ORDER BY
+			role;
+	`, id)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	roles := make([]string, 0, 1)
+
+	for rows.Next() {
+		var role string
+
+		err := rows.Scan(&role)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		roles = append(roles, role)
+	}
+
+	if rows.Err() != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, rows.Err())
+	}
+
+	return roles, nil
+}
+
+// AddUserRole grants a role to the user, doing nothing if they already have it.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r PostgresUserRepository) AddUserRole(id int, role string) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		INSERT INTO user_roles(user_id, role)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;
+	`, id, role)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// RemoveUserRole revokes a role from the user.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// user not having the role.
+func (r PostgresUserRepository) RemoveUserRole(id int, role string) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		DELETE FROM
+			user_roles
+		WHERE
+			user_id = $1 AND
+			role = $2;
+	`, id, role)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(
+			http.StatusNotFound,
+			fmt.Sprintf("User ID %d doesn't have the role '%s'", id, role),
+		)
+	}
+
+	return nil
+}
+
+// UpdateUserHash changes the password hash for the given id.
+//
+// Errors can be caused by:
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// id not being found.
+func (r PostgresUserRepository) UpdateUserHash(id int, newHash string) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			hash = $1
+		WHERE
+			id = $2;
+	`, newHash, id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, fmt.Sprintf("User ID %d doesn't exist", id))
+	}
+
+	return nil
+}
+
+// VerifyUserEmail marks the user email address as verified, keeping the
+// first verification time of already verified users.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r PostgresUserRepository) VerifyUserEmail(id int, verifiedAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			verified_at = $1
+		WHERE
+			id = $2 AND
+			verified_at IS NULL;
+	`, verifiedAt, id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// UpdateUserEmail changes the user email address, marking it as verified
+// since the user confirmed owning it.
+//
+// Errors can be caused by:
+// email address already being used;
+// query not being sucessfully executed;
+// fail to get number of affected rows;
+// id not being found.
+func (r PostgresUserRepository) UpdateUserEmail(id int, newEmail string, verifiedAt time.Time) *utils.ErrorCode {
+	result, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			email = $1,
+			verified_at = $2
+		WHERE
+			id = $3;
+	`, newEmail, verifiedAt, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return utils.NewErrorCodeString(http.StatusConflict, "Email address already exist")
+		}
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	if rowsAffected == 0 {
+		return utils.NewErrorCodeString(http.StatusNotFound, fmt.Sprintf("User ID %d doesn't exist", id))
+	}
+
+	return nil
+}
+
+// UpdateUserLastLogin records when the user last logged in.
+//
+// Errors can be caused by:
+// query not being sucessfully executed.
+func (r PostgresUserRepository) UpdateUserLastLogin(id int, lastLoginAt time.Time) *utils.ErrorCode {
+	_, err := r.db.Exec(`
+		UPDATE
+			users
+		SET
+			last_login_at = $1
+		WHERE
+			id = $2;
+	`, lastLoginAt, id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
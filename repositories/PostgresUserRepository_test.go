@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+)
+
+// recordingDB records the queries sent to it along with their arguments,
+// answering them with no rows but a zero count.
+type recordingDB struct {
+	queries []string
+	args    [][]driver.Value
+}
+
+func (d *recordingDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &recordingConn{db: d}, nil
+}
+
+func (d *recordingDB) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	db *recordingDB
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions aren't recorded")
+}
+
+type recordingStmt struct {
+	db    *recordingDB
+	query string
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.queries = append(s.db.queries, s.query)
+	s.db.args = append(s.db.args, args)
+
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.queries = append(s.db.queries, s.query)
+	s.db.args = append(s.db.args, args)
+
+	return &countRows{counted: !strings.Contains(s.query, "count(*)")}, nil
+}
+
+// countRows is a single zero count.
+type countRows struct {
+	counted bool
+}
+
+func (r *countRows) Columns() []string {
+	return []string{"count"}
+}
+
+func (r *countRows) Close() error {
+	return nil
+}
+
+func (r *countRows) Next(dest []driver.Value) error {
+	if r.counted {
+		return io.EOF
+	}
+	r.counted = true
+
+	dest[0] = int64(0)
+
+	return nil
+}
+
+func newRecordingUserRepository() (*PostgresUserRepository, *recordingDB) {
+	recorder := new(recordingDB)
+
+	return &PostgresUserRepository{db: sql.OpenDB(recorder)}, recorder
+}
+
+func TestPostgresUserRepository_SelectUsers(t *testing.T) {
+	testCases := []struct {
+		query dtos.UserQuery
+		where string
+		order string
+		args  string
+	}{
+		{
+			dtos.UserQuery{Sort: "id", Limit: 20},
+			"FROM users ORDER BY",
+			"ORDER BY id ASC LIMIT $1 OFFSET $2;",
+			"[20 0]",
+		},
+		{
+			dtos.UserQuery{Sort: "-username", Limit: 10, Offset: 5, EmailDomain: "Mail.com", UsernamePrefix: "di_"},
+			"WHERE email ILIKE $1 AND username ILIKE $2 ORDER BY",
+			"ORDER BY username DESC, id DESC LIMIT $3 OFFSET $4;",
+			`[%@Mail.com di\_% 10 5]`,
+		},
+		{
+			dtos.UserQuery{Sort: "username", Limit: 10, EmailDomain: "mail.com", Cursor: &dtos.Cursor{Sort: "username", Key: "diego", ID: 3}},
+			"WHERE email ILIKE $1 AND (username > $2 OR (username = $3 AND id > $4)) ORDER BY",
+			"ORDER BY username ASC, id ASC LIMIT $5 OFFSET $6;",
+			"[%@mail.com diego diego 3 10 0]",
+		},
+		{
+			dtos.UserQuery{Sort: "id", Limit: 10, Cursor: &dtos.Cursor{Sort: "id", ID: 7, Backward: true}},
+			"WHERE id < $1 ORDER BY",
+			"ORDER BY id DESC LIMIT $2 OFFSET $3;",
+			"[7 10 0]",
+		},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			repo, recorder := newRecordingUserRepository()
+
+			_, _, err := repo.SelectUsers(&_case.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(recorder.queries) != 2 {
+				t.Fatalf("Users should be counted then selected, got %q", recorder.queries)
+			}
+
+			selectQuery := recorder.queries[1]
+
+			if strings.Contains(selectQuery, "?") {
+				t.Errorf("Placeholders should be numbered, got '%s'", selectQuery)
+			}
+			if !strings.Contains(selectQuery, _case.where) {
+				t.Errorf("Query should contain '%s', got '%s'", _case.where, selectQuery)
+			}
+			if !strings.HasSuffix(selectQuery, _case.order) {
+				t.Errorf("Query should end with '%s', got '%s'", _case.order, selectQuery)
+			}
+			if args := fmt.Sprint(recorder.args[1]); args != _case.args {
+				t.Errorf("Arguments should be '%s', got '%s'", _case.args, args)
+			}
+		})
+	}
+}
+
+func TestPostgresUserRepository_SelectUserFromEmail(t *testing.T) {
+	repo, recorder := newRecordingUserRepository()
+
+	_, err := repo.SelectUserFromEmail("Diego@Mail.com")
+	if err == nil {
+		t.Fatal("Unknown email should not be found")
+	}
+
+	if len(recorder.queries) != 1 || !strings.HasSuffix(recorder.queries[0], "WHERE lower(email) = lower($1);") {
+		t.Errorf("Emails should be compared whatever their case, got %q", recorder.queries)
+	}
+}
@@ -16,9 +16,16 @@ type DefaultRouter struct {
 	endpointPrefix   string
 	engine           *gin.Engine
 	routeControllers []interfaces.RouteController
+	rootControllers  []interfaces.RouteController
 }
 
-func NewDefaultV1Router(endpointPrefix string, routeControllers []interfaces.RouteController) interfaces.Router {
+// NewDefaultV1Router attaches routeControllers under the versioned endpoint
+// prefix and rootControllers, such as the "/.well-known" ones, at the root.
+func NewDefaultV1Router(
+	endpointPrefix string,
+	routeControllers []interfaces.RouteController,
+	rootControllers []interfaces.RouteController,
+) interfaces.Router {
 	version := "v1"
 
 	router := &DefaultRouter{
@@ -26,6 +33,7 @@ func NewDefaultV1Router(endpointPrefix string, routeControllers []interfaces.Rou
 		endpointPrefix:   endpointPrefix + "/" + version,
 		engine:           gin.Default(),
 		routeControllers: routeControllers,
+		rootControllers:  rootControllers,
 	}
 
 	router.engine.Static(endpointPrefix+"/docs", "routers/docs")
@@ -43,6 +51,10 @@ func (r DefaultRouter) setupRoutes() {
 	for _, group := range r.routeControllers {
 		group.AttachTo(endpoint)
 	}
+
+	for _, group := range r.rootControllers {
+		group.AttachTo(&r.engine.RouterGroup)
+	}
 }
 
 func (r DefaultRouter) ping(ctx *gin.Context) {
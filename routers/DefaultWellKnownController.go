@@ -0,0 +1,76 @@
+package routers
+
+import (
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultWellKnownController implements RouteController
+var _ interfaces.RouteController = (*DefaultWellKnownController)(nil)
+
+// DefaultWellKnownController serves the unversioned "/.well-known" metadata
+// other services rely on.
+type DefaultWellKnownController struct {
+	auth         interfaces.Authenticator
+	openIDConfig *dtos.OpenIDConfiguration
+}
+
+// NewDefaultWellKnownController describes the OAuth endpoints found under
+// the versioned endpointPrefix, such as "/api/v1".
+func NewDefaultWellKnownController(
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+	endpointPrefix string,
+) interfaces.RouteController {
+	return &DefaultWellKnownController{
+		auth:         authenticator,
+		openIDConfig: newOpenIDConfiguration(settings, endpointPrefix),
+	}
+}
+
+func (c DefaultWellKnownController) AttachTo(group *gin.RouterGroup) {
+	group.GET("/.well-known/jwks.json", c.jwks)
+	group.GET("/.well-known/openid-configuration", c.openIDConfiguration)
+}
+
+func (c DefaultWellKnownController) jwks(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.auth.JSONWebKeySet())
+}
+
+func (c DefaultWellKnownController) openIDConfiguration(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.openIDConfig)
+}
+
+// newOpenIDConfiguration returns the discovery document of the OpenID
+// Connect provider. Relying parties expect the issuer to be the URL serving
+// it.
+func newOpenIDConfiguration(settings *config.Settings, endpointPrefix string) *dtos.OpenIDConfiguration {
+	scopes := []string{models.ScopeOpenID, models.ScopeProfile, models.ScopeEmail}
+	scopes = append(scopes, authorization.KnownPermissions()...)
+
+	return &dtos.OpenIDConfiguration{
+		Issuer:                            settings.Auth.Issuer,
+		AuthorizationEndpoint:             settings.Api.URL(endpointPrefix + "/oauth/authorize"),
+		TokenEndpoint:                     settings.Api.URL(endpointPrefix + "/oauth/token"),
+		UserinfoEndpoint:                  settings.Api.URL(endpointPrefix + "/userinfo"),
+		JWKSURI:                           settings.Api.URL("/.well-known/jwks.json"),
+		IntrospectionEndpoint:             settings.Api.URL(endpointPrefix + "/oauth/introspect"),
+		RevocationEndpoint:                settings.Api.URL(endpointPrefix + "/oauth/revoke"),
+		ScopesSupported:                   scopes,
+		ResponseTypesSupported:            []string{models.ResponseTypeCode},
+		GrantTypesSupported:               []string{models.GrantAuthorizationCode, models.GrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"EdDSA"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{models.CodeChallengeS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "email", "email_verified", "preferred_username"},
+	}
+}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/middlewares/validate"
+	"github.com/d1360-64rc14/simple-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAPIKeyController implements RouteController
+var _ interfaces.RouteController = (*DefaultAPIKeyController)(nil)
+
+type DefaultAPIKeyController struct {
+	service  interfaces.APIKeyService
+	repo     interfaces.UserRepository
+	auth     interfaces.Authenticator
+	settings *config.Settings
+}
+
+func NewDefaultAPIKeyController(
+	apiKeyService interfaces.APIKeyService,
+	userRepository interfaces.UserRepository,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.RouteController {
+	return &DefaultAPIKeyController{
+		service:  apiKeyService,
+		repo:     userRepository,
+		auth:     authenticator,
+		settings: settings,
+	}
+}
+
+func (c DefaultAPIKeyController) AttachTo(group *gin.RouterGroup) {
+	authenticated := middlewares.Authenticate(c.auth)
+	canRead := middlewares.RequirePermission(authorization.PermUsersRead)
+	canUpdate := middlewares.RequirePermission(authorization.PermUsersUpdate)
+
+	group.GET("/user/:id/api-keys", authenticated, canRead, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.list)
+	group.POST("/user/:id/api-keys", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.create)
+	group.DELETE("/user/:id/api-keys/:keyId", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.revoke)
+}
+
+func (c DefaultAPIKeyController) list(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	keys, err := c.service.SelectUserAPIKeys(id)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+func (c DefaultAPIKeyController) create(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	// Otherwise a scoped key or OAuth token could create itself an unscoped key
+	if middlewares.AuthClaims(ctx).IsDelegated() {
+		ctx.JSON(http.StatusForbidden, dtos.NewErrorMessageString("API keys and OAuth tokens cannot create API keys"))
+		return
+	}
+
+	var keyData dtos.APIKeyRequest
+
+	if err := ctx.ShouldBindJSON(&keyData); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	key, err := c.service.CreateAPIKey(id, &keyData)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+func (c DefaultAPIKeyController) revoke(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	keyIdString := ctx.Param("keyId")
+	keyId, parseErr := strconv.ParseInt(keyIdString, 10, 32)
+	if parseErr != nil {
+		ctx.JSON(
+			http.StatusBadRequest,
+			dtos.NewErrorMessageString(fmt.Sprintf("The API key ID in the path should be an integer, not '%s'", keyIdString)),
+		)
+		return
+	}
+
+	err := c.service.RevokeAPIKey(id, int(keyId))
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
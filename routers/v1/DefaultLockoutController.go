@@ -0,0 +1,61 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLockoutController implements RouteController
+var _ interfaces.RouteController = (*DefaultLockoutController)(nil)
+
+type DefaultLockoutController struct {
+	service  interfaces.LockoutService
+	auth     interfaces.Authenticator
+	settings *config.Settings
+}
+
+func NewDefaultLockoutController(
+	lockoutService interfaces.LockoutService,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.RouteController {
+	return &DefaultLockoutController{
+		service:  lockoutService,
+		auth:     authenticator,
+		settings: settings,
+	}
+}
+
+func (c DefaultLockoutController) AttachTo(group *gin.RouterGroup) {
+	authenticated := middlewares.Authenticate(c.auth)
+	canManageLockouts := middlewares.RequirePermission(authorization.PermLockoutsManage)
+
+	group.GET("/lockouts", authenticated, canManageLockouts, c.list)
+	group.DELETE("/lockouts/:kind/:subject", authenticated, canManageLockouts, c.clear)
+}
+
+func (c DefaultLockoutController) list(ctx *gin.Context) {
+	lockouts, err := c.service.SelectActiveLockouts()
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lockouts)
+}
+
+func (c DefaultLockoutController) clear(ctx *gin.Context) {
+	err := c.service.ClearLockout(ctx.Param("kind"), ctx.Param("subject"))
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
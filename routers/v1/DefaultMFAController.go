@@ -0,0 +1,118 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/middlewares/validate"
+	"github.com/d1360-64rc14/simple-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMFAController implements RouteController
+var _ interfaces.RouteController = (*DefaultMFAController)(nil)
+
+type DefaultMFAController struct {
+	service  interfaces.MFAService
+	repo     interfaces.UserRepository
+	auth     interfaces.Authenticator
+	settings *config.Settings
+}
+
+func NewDefaultMFAController(
+	mfaService interfaces.MFAService,
+	userRepository interfaces.UserRepository,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.RouteController {
+	return &DefaultMFAController{
+		service:  mfaService,
+		repo:     userRepository,
+		auth:     authenticator,
+		settings: settings,
+	}
+}
+
+func (c DefaultMFAController) AttachTo(group *gin.RouterGroup) {
+	authenticated := middlewares.Authenticate(c.auth)
+	canUpdate := middlewares.RequirePermission(authorization.PermUsersUpdate)
+
+	group.POST("/user/login/mfa", c.login)
+	group.POST("/user/:id/mfa/totp", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.enroll)
+	group.POST("/user/:id/mfa/totp/confirm", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.confirm)
+	group.DELETE("/user/:id/mfa/totp", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.disable)
+}
+
+func (c DefaultMFAController) login(ctx *gin.Context) {
+	var loginData dtos.MFALoginRequest
+
+	if err := ctx.ShouldBindJSON(&loginData); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	loginData.ClientIP = ctx.ClientIP()
+	loginData.UserAgent = ctx.Request.UserAgent()
+
+	tokenRes, err := c.service.CompleteLogin(&loginData)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokenRes)
+}
+
+func (c DefaultMFAController) enroll(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	enrolment, err := c.service.EnrollTOTP(id)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrolment)
+}
+
+func (c DefaultMFAController) confirm(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	var codeData dtos.MFACode
+
+	if err := ctx.ShouldBindJSON(&codeData); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	recoveryCodes, err := c.service.ConfirmTOTP(id, codeData.Code)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, recoveryCodes)
+}
+
+func (c DefaultMFAController) disable(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	var codeData dtos.MFACode
+
+	if err := ctx.ShouldBindJSON(&codeData); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.DisableTOTP(id, codeData.Code)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
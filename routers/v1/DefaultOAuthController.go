@@ -0,0 +1,235 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultOAuthController implements RouteController
+var _ interfaces.RouteController = (*DefaultOAuthController)(nil)
+
+type DefaultOAuthController struct {
+	service  interfaces.OAuthService
+	auth     interfaces.Authenticator
+	settings *config.Settings
+}
+
+func NewDefaultOAuthController(
+	oauthService interfaces.OAuthService,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.RouteController {
+	return &DefaultOAuthController{
+		service:  oauthService,
+		auth:     authenticator,
+		settings: settings,
+	}
+}
+
+func (c DefaultOAuthController) AttachTo(group *gin.RouterGroup) {
+	authenticated := middlewares.Authenticate(c.auth)
+	canManageClients := middlewares.RequirePermission(authorization.PermOAuthClientsManage)
+
+	group.GET("/oauth/clients", authenticated, canManageClients, c.listClients)
+	group.POST("/oauth/clients", authenticated, canManageClients, c.registerClient)
+	group.DELETE("/oauth/clients/:clientId", authenticated, canManageClients, c.removeClient)
+
+	group.POST("/oauth/authorize", authenticated, c.authorize)
+	group.POST("/oauth/token", c.token)
+	group.POST("/oauth/introspect", c.introspect)
+	group.POST("/oauth/revoke", c.revoke)
+
+	group.GET("/userinfo", authenticated, c.userInfo)
+	group.POST("/userinfo", authenticated, c.userInfo)
+}
+
+func (c DefaultOAuthController) listClients(ctx *gin.Context) {
+	clients, err := c.service.SelectClients()
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, clients)
+}
+
+func (c DefaultOAuthController) registerClient(ctx *gin.Context) {
+	var clientData dtos.OAuthClientRequest
+
+	if err := ctx.ShouldBindJSON(&clientData); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	client, err := c.service.RegisterClient(&clientData)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, client)
+}
+
+func (c DefaultOAuthController) removeClient(ctx *gin.Context) {
+	err := c.service.RemoveClient(ctx.Param("clientId"))
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c DefaultOAuthController) authorize(ctx *gin.Context) {
+	var authorizeData dtos.OAuthAuthorizeRequest
+
+	if err := ctx.ShouldBind(&authorizeData); err != nil {
+		invalidOAuthRequest(ctx, err.Error())
+		return
+	}
+
+	granted, err := c.service.Authorize(middlewares.AuthClaims(ctx), &authorizeData)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, granted)
+}
+
+func (c DefaultOAuthController) token(ctx *gin.Context) {
+	var tokenData dtos.OAuthTokenRequest
+
+	if err := ctx.ShouldBind(&tokenData); err != nil {
+		invalidOAuthRequest(ctx, err.Error())
+		return
+	}
+
+	if !basicClientAuth(ctx, &tokenData.ClientID, &tokenData.ClientSecret) {
+		return
+	}
+
+	token, err := c.service.Token(&tokenData)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(http.StatusOK, token)
+}
+
+func (c DefaultOAuthController) introspect(ctx *gin.Context) {
+	var lookupData dtos.OAuthTokenLookup
+
+	if err := ctx.ShouldBind(&lookupData); err != nil {
+		invalidOAuthRequest(ctx, err.Error())
+		return
+	}
+
+	if !basicClientAuth(ctx, &lookupData.ClientID, &lookupData.ClientSecret) {
+		return
+	}
+
+	introspection, err := c.service.Introspect(&lookupData)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, introspection)
+}
+
+func (c DefaultOAuthController) revoke(ctx *gin.Context) {
+	var lookupData dtos.OAuthTokenLookup
+
+	if err := ctx.ShouldBind(&lookupData); err != nil {
+		invalidOAuthRequest(ctx, err.Error())
+		return
+	}
+
+	if !basicClientAuth(ctx, &lookupData.ClientID, &lookupData.ClientSecret) {
+		return
+	}
+
+	err := c.service.Revoke(&lookupData)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (c DefaultOAuthController) userInfo(ctx *gin.Context) {
+	claims, err := c.service.UserInfo(middlewares.AuthClaims(ctx))
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, claims)
+}
+
+// basicClientAuth reads the client credentials of the HTTP Basic header,
+// form encoded as RFC 6749 wants them, into clientId and clientSecret.
+//
+// Responds with 400 and returns false when the client also authenticates
+// through the form.
+func basicClientAuth(ctx *gin.Context, clientId *string, clientSecret *string) bool {
+	username, password, ok := ctx.Request.BasicAuth()
+	if !ok {
+		return true
+	}
+
+	if *clientSecret != "" {
+		invalidOAuthRequest(ctx, "Clients must authenticate with a single method")
+		return false
+	}
+
+	id, idErr := url.QueryUnescape(username)
+	secret, secretErr := url.QueryUnescape(password)
+	if idErr != nil || secretErr != nil {
+		invalidOAuthRequest(ctx, "Malformed client credentials")
+		return false
+	}
+
+	*clientId = id
+	*clientSecret = secret
+
+	return true
+}
+
+// oauthErrorResponse sends err as an OAuth error response, hiding the
+// details of internal errors.
+func oauthErrorResponse(ctx *gin.Context, err *utils.ErrorCode) {
+	if err.Code() >= http.StatusInternalServerError {
+		ctx.JSON(err.Code(), dtos.OAuthError{Error: models.OAuthServerError})
+		return
+	}
+
+	if err.Code() == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", `Basic realm="simple-api"`)
+	}
+
+	ctx.JSON(err.Code(), dtos.NewOAuthError(err))
+}
+
+func invalidOAuthRequest(ctx *gin.Context, description string) {
+	ctx.JSON(http.StatusBadRequest, dtos.OAuthError{
+		Error:            models.OAuthInvalidRequest,
+		ErrorDescription: description,
+	})
+}
@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/middlewares/validate"
+	"github.com/d1360-64rc14/simple-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultSessionController implements RouteController
+var _ interfaces.RouteController = (*DefaultSessionController)(nil)
+
+type DefaultSessionController struct {
+	service  interfaces.TokenService
+	repo     interfaces.UserRepository
+	auth     interfaces.Authenticator
+	settings *config.Settings
+}
+
+func NewDefaultSessionController(
+	tokenService interfaces.TokenService,
+	userRepository interfaces.UserRepository,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.RouteController {
+	return &DefaultSessionController{
+		service:  tokenService,
+		repo:     userRepository,
+		auth:     authenticator,
+		settings: settings,
+	}
+}
+
+func (c DefaultSessionController) AttachTo(group *gin.RouterGroup) {
+	authenticated := middlewares.Authenticate(c.auth)
+	canRead := middlewares.RequirePermission(authorization.PermUsersRead)
+	canUpdate := middlewares.RequirePermission(authorization.PermUsersUpdate)
+
+	group.GET("/user/:id/sessions", authenticated, canRead, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.list)
+	group.DELETE("/user/:id/sessions/:sid", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.revoke)
+}
+
+func (c DefaultSessionController) list(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	sessions, err := c.service.SelectUserSessions(id)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	currentId := middlewares.AuthClaims(ctx).SessionID
+	for _, session := range sessions {
+		session.Current = currentId != "" && session.ID == currentId
+	}
+
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+func (c DefaultSessionController) revoke(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	err := c.service.RevokeSession(id, ctx.Param("sid"))
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/middlewares"
+	"github.com/d1360-64rc14/simple-api/middlewares/validate"
+	"github.com/d1360-64rc14/simple-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultTokenController implements RouteController
+var _ interfaces.RouteController = (*DefaultTokenController)(nil)
+
+type DefaultTokenController struct {
+	service  interfaces.TokenService
+	repo     interfaces.UserRepository
+	auth     interfaces.Authenticator
+	settings *config.Settings
+}
+
+func NewDefaultTokenController(
+	tokenService interfaces.TokenService,
+	userRepository interfaces.UserRepository,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.RouteController {
+	return &DefaultTokenController{
+		service:  tokenService,
+		repo:     userRepository,
+		auth:     authenticator,
+		settings: settings,
+	}
+}
+
+func (c DefaultTokenController) AttachTo(group *gin.RouterGroup) {
+	authenticated := middlewares.Authenticate(c.auth)
+
+	group.POST("/token/refresh", c.refresh)
+	group.POST("/user/logout", authenticated, c.logout)
+	group.POST("/user/:id/logout-all", authenticated, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.logoutAll)
+}
+
+func (c DefaultTokenController) refresh(ctx *gin.Context) {
+	var refreshData dtos.RefreshRequest
+
+	if err := ctx.ShouldBindJSON(&refreshData); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	tokenRes, err := c.service.RefreshTokens(refreshData.RefreshToken)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokenRes)
+}
+
+func (c DefaultTokenController) logout(ctx *gin.Context) {
+	var logoutData dtos.LogoutRequest
+
+	// The body is optional
+	if err := ctx.ShouldBindJSON(&logoutData); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.Logout(middlewares.AuthClaims(ctx), logoutData.RefreshToken)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c DefaultTokenController) logoutAll(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	err := c.service.LogoutAll(id)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
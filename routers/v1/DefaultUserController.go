@@ -3,11 +3,15 @@ package v1
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/d1360-64rc14/simple-api/authorization"
 	"github.com/d1360-64rc14/simple-api/config"
 	"github.com/d1360-64rc14/simple-api/dtos"
 	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/middlewares"
 	"github.com/d1360-64rc14/simple-api/middlewares/validate"
+	"github.com/d1360-64rc14/simple-api/models"
 	"github.com/d1360-64rc14/simple-api/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -18,38 +22,96 @@ var _ interfaces.RouteController = (*DefaultUserController)(nil)
 type DefaultUserController struct {
 	service  interfaces.UserService
 	repo     interfaces.UserRepository
+	auth     interfaces.Authenticator
+	cursors  interfaces.CursorSigner
 	settings *config.Settings
 }
 
 func NewDefaultUserController(
 	userService interfaces.UserService,
 	userRepository interfaces.UserRepository,
+	authenticator interfaces.Authenticator,
+	cursorSigner interfaces.CursorSigner,
 	settings *config.Settings,
 ) interfaces.RouteController {
 	return &DefaultUserController{
 		service:  userService,
 		repo:     userRepository,
+		auth:     authenticator,
+		cursors:  cursorSigner,
 		settings: settings,
 	}
 }
 
 func (c DefaultUserController) AttachTo(group *gin.RouterGroup) {
-	group.GET("/user/:id", validate.PathUserId, validate.UserIdExist(c.repo), c.get)
-	group.GET("/users", c.getAll)
+	authenticated := middlewares.Authenticate(c.auth)
+	canRead := middlewares.RequirePermission(authorization.PermUsersRead)
+	canList := middlewares.RequirePermission(authorization.PermUsersList)
+	canUpdate := middlewares.RequirePermission(authorization.PermUsersUpdate)
+	canDelete := middlewares.RequirePermission(authorization.PermUsersDelete)
+	canManageRoles := middlewares.RequirePermission(authorization.PermRolesManage)
+
+	group.GET("/user/:id", authenticated, canRead, validate.PathUserId, validate.UserIdExist(c.repo), c.get)
+	group.GET("/users", authenticated, canList, validate.QueryCursor(c.cursors), c.getAll)
+	group.GET("/users/search", authenticated, canList, validate.QueryHave("q"), c.search)
 	group.POST("/user", c.create)
-	group.PATCH("/user/:id", validate.PathUserId, validate.UserIdExist(c.repo), c.update)
-	group.DELETE("/user/:id", validate.PathUserId, validate.UserIdExist(c.repo), c.delete)
+	group.PATCH("/user/:id", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.update)
+	group.DELETE("/user/:id", authenticated, canDelete, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.delete)
+	group.PUT("/user/:id/password", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.changePassword)
+	group.POST("/user/:id/email", authenticated, canUpdate, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), c.requestEmailChange)
+	group.GET("/user/email/confirm", validate.QueryHave("token"), c.confirmEmailChange)
+	group.POST("/user/password/forgot", c.forgotPassword)
+	group.POST("/user/password/reset", c.resetPassword)
+	group.GET("/user/verify", validate.QueryHave("token"), c.verifyEmail)
+	group.POST("/user/verify/resend", c.resendVerification)
 	group.POST("/user/login", c.login)
+	group.GET("/user/:id/logins", authenticated, canRead, validate.PathUserId, validate.UserIsCaller, validate.UserIdExist(c.repo), validate.QueryCursor(c.cursors), c.getLogins)
+	group.GET("/user/:id/roles", authenticated, canManageRoles, validate.PathUserId, validate.UserIdExist(c.repo), c.getRoles)
+	group.POST("/user/:id/roles", authenticated, canManageRoles, validate.PathUserId, validate.UserIdExist(c.repo), c.grantRole)
+	group.DELETE("/user/:id/roles/:role", authenticated, canManageRoles, validate.PathUserId, validate.UserIdExist(c.repo), c.revokeRole)
 }
 
 func (c DefaultUserController) getAll(ctx *gin.Context) {
-	allUsers, err := c.service.SelectAllUsers()
+	var userQuery dtos.UserQuery
+
+	if err := ctx.ShouldBindQuery(&userQuery); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	userQuery.Cursor = validate.Cursor(ctx)
+
+	users, err := c.service.SelectUsers(&userQuery)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	if users.NextCursor != "" {
+		users.Next = c.pageLink(ctx, users.NextCursor, users.Limit)
+	}
+	if users.PrevCursor != "" {
+		users.Prev = c.pageLink(ctx, users.PrevCursor, users.Limit)
+	}
+
+	ctx.JSON(http.StatusOK, users)
+}
+
+func (c DefaultUserController) search(ctx *gin.Context) {
+	var searchQuery dtos.UserSearchQuery
+
+	if err := ctx.ShouldBindQuery(&searchQuery); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	users, err := c.service.SearchUsers(&searchQuery)
 	if err != nil {
 		utils.ErrorResponse(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, allUsers)
+	ctx.JSON(http.StatusOK, users)
 }
 
 func (c DefaultUserController) get(ctx *gin.Context) {
@@ -61,6 +123,12 @@ func (c DefaultUserController) get(ctx *gin.Context) {
 		return
 	}
 
+	// When a user last logged in is only told to them and admins
+	claims := middlewares.AuthClaims(ctx)
+	if claims.UserID != id && !claims.HasRole(models.RoleAdmin) {
+		user.LastLoginAt = nil
+	}
+
 	ctx.JSON(http.StatusOK, user)
 }
 
@@ -78,13 +146,7 @@ func (c DefaultUserController) create(ctx *gin.Context) {
 		return
 	}
 
-	newUserLocation := fmt.Sprintf(
-		"%s://%s%s/%d",
-		c.settings.Api.Protocol,
-		c.settings.Api.BaseUrl,
-		ctx.Request.URL.Path,
-		user.ID,
-	)
+	newUserLocation := c.settings.Api.URL(fmt.Sprintf("%s/%d", ctx.Request.URL.Path, user.ID))
 	ctx.Header("Location", newUserLocation)
 
 	ctx.JSON(http.StatusCreated, user)
@@ -109,6 +171,115 @@ func (c DefaultUserController) update(ctx *gin.Context) {
 	ctx.Status(http.StatusNoContent)
 }
 
+func (c DefaultUserController) changePassword(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	var passwordChange dtos.PasswordChange
+
+	if err := ctx.ShouldBindJSON(&passwordChange); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.ChangePassword(id, &passwordChange)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c DefaultUserController) requestEmailChange(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	var emailChange dtos.EmailChange
+
+	if err := ctx.ShouldBindJSON(&emailChange); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.RequestEmailChange(id, &emailChange)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (c DefaultUserController) confirmEmailChange(ctx *gin.Context) {
+	err := c.service.ConfirmEmailChange(ctx.Query("token"))
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c DefaultUserController) forgotPassword(ctx *gin.Context) {
+	var passwordForgot dtos.PasswordForgot
+
+	if err := ctx.ShouldBindJSON(&passwordForgot); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.ForgotPassword(&passwordForgot)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (c DefaultUserController) resetPassword(ctx *gin.Context) {
+	var passwordReset dtos.PasswordReset
+
+	if err := ctx.ShouldBindJSON(&passwordReset); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.ResetPassword(&passwordReset)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c DefaultUserController) verifyEmail(ctx *gin.Context) {
+	err := c.service.VerifyEmail(ctx.Query("token"))
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c DefaultUserController) resendVerification(ctx *gin.Context) {
+	var verificationResend dtos.VerificationResend
+
+	if err := ctx.ShouldBindJSON(&verificationResend); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.ResendVerification(&verificationResend)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
 func (c DefaultUserController) delete(ctx *gin.Context) {
 	id := ctx.GetInt("id")
 
@@ -129,10 +300,103 @@ func (c DefaultUserController) login(ctx *gin.Context) {
 		return
 	}
 
+	authData.ClientIP = ctx.ClientIP()
+	authData.UserAgent = ctx.Request.UserAgent()
+
 	tokenRes, err := c.service.LoginUser(&authData)
 	if err != nil {
 		utils.ErrorResponse(ctx, err)
+		return
 	}
 
 	ctx.JSON(http.StatusOK, tokenRes)
 }
+
+func (c DefaultUserController) getLogins(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	var historyQuery dtos.LoginHistoryQuery
+
+	if err := ctx.ShouldBindQuery(&historyQuery); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	historyQuery.Cursor = validate.Cursor(ctx)
+
+	logins, err := c.service.SelectUserLogins(id, &historyQuery)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	if logins.NextCursor != "" {
+		logins.Next = c.pageLink(ctx, logins.NextCursor, logins.Limit)
+	}
+	if logins.PrevCursor != "" {
+		logins.Prev = c.pageLink(ctx, logins.PrevCursor, logins.Limit)
+	}
+
+	ctx.JSON(http.StatusOK, logins)
+}
+
+// pageLink returns the absolute URL of the current request, with the rest
+// of its query kept, moved to the page at cursor.
+func (c DefaultUserController) pageLink(ctx *gin.Context, cursor string, limit int) string {
+	query := ctx.Request.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+
+	return c.settings.Api.URL(ctx.Request.URL.Path + "?" + query.Encode())
+}
+
+func (c DefaultUserController) getRoles(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	roles, err := c.service.SelectUserRoles(id)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+func (c DefaultUserController) grantRole(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+
+	var roleData dtos.RoleRequest
+
+	if err := ctx.ShouldBindJSON(&roleData); err != nil {
+		ctx.JSON(http.StatusBadRequest, dtos.NewErrorMessage(err))
+		return
+	}
+
+	err := c.service.GrantRole(id, roleData.Role)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c DefaultUserController) revokeRole(ctx *gin.Context) {
+	id := ctx.GetInt("id")
+	role := ctx.Param("role")
+
+	// Otherwise the last administrator could lock everyone out
+	if role == models.RoleAdmin && id == ctx.GetInt(middlewares.AuthUserIdKey) {
+		ctx.JSON(http.StatusConflict, dtos.NewErrorMessageString("Administrators cannot revoke their own admin role"))
+		return
+	}
+
+	err := c.service.RevokeRole(id, role)
+	if err != nil {
+		utils.ErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// DefaultAPIKeyService implements APIKeyService
+var _ interfaces.APIKeyService = (*DefaultAPIKeyService)(nil)
+
+type DefaultAPIKeyService struct {
+	repo     interfaces.APIKeyRepository
+	settings *config.Settings
+	now      func() time.Time
+}
+
+func NewDefaultAPIKeyService(
+	apiKeyRepository interfaces.APIKeyRepository,
+	settings *config.Settings,
+) interfaces.APIKeyService {
+	return &DefaultAPIKeyService{
+		repo:     apiKeyRepository,
+		settings: settings,
+		now:      time.Now,
+	}
+}
+
+// CreateAPIKey creates an API key for the user, returning the whole key for
+// the only time. Scopes must be permissions, and the expiration, if any, in
+// the future.
+func (s DefaultAPIKeyService) CreateAPIKey(userId int, request *dtos.APIKeyRequest) (*dtos.CreatedAPIKey, *utils.ErrorCode) {
+	now := s.now().UTC()
+
+	scopes := make([]string, 0, len(request.Scopes))
+	for _, scope := range request.Scopes {
+		if !authorization.IsKnownPermission(scope) {
+			return nil, utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Unknown scope '%s'", scope))
+		}
+		if !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	var expiresAt *time.Time
+	if request.ExpiresAt != nil {
+		if !request.ExpiresAt.After(now) {
+			return nil, utils.NewErrorCodeString(http.StatusBadRequest, "Expiration must be in the future")
+		}
+
+		utcExpiresAt := request.ExpiresAt.UTC()
+		expiresAt = &utcExpiresAt
+	}
+
+	secret, err := utils.NewRandomToken(32)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	key := models.APIKeyPrefix + secret
+
+	created, errC := s.repo.CreateAPIKey(&dtos.APIKey{
+		UserID:    userId,
+		Name:      request.Name,
+		Prefix:    key[:models.APIKeyVisibleLength],
+		Hash:      utils.HashToken(key),
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	})
+	if errC != nil {
+		return nil, errC
+	}
+
+	return &dtos.CreatedAPIKey{
+		APIKey: *created,
+		Key:    key,
+	}, nil
+}
+
+func (s DefaultAPIKeyService) SelectUserAPIKeys(userId int) ([]*dtos.APIKey, *utils.ErrorCode) {
+	return s.repo.SelectUserAPIKeys(userId)
+}
+
+func (s DefaultAPIKeyService) RevokeAPIKey(userId int, id int) *utils.ErrorCode {
+	return s.repo.RevokeAPIKey(userId, id, s.now().UTC())
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
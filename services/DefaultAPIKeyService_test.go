@@ -0,0 +1,73 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+func TestAPIKeys(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	repo := mocks.NewMockedAPIKeyRepository()
+	service := NewDefaultAPIKeyService(repo, testSettings).(*DefaultAPIKeyService)
+	service.now = func() time.Time { return now }
+
+	past := now.Add(-time.Hour)
+	_, err := service.CreateAPIKey(1, &dtos.APIKeyRequest{Name: "ci", ExpiresAt: &past})
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Past expiration should be rejected with 400, got %v", err)
+	}
+
+	_, err = service.CreateAPIKey(1, &dtos.APIKeyRequest{Name: "ci", Scopes: []string{"everything"}})
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Unknown scope should be rejected with 400, got %v", err)
+	}
+
+	created, err := service.CreateAPIKey(1, &dtos.APIKeyRequest{
+		Name:   "ci",
+		Scopes: []string{authorization.PermUsersRead, authorization.PermUsersRead},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(created.Key, models.APIKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Errorf("Key '%s' should start with '%s' and its prefix '%s'", created.Key, models.APIKeyPrefix, created.Prefix)
+	}
+	if len(created.Scopes) != 1 {
+		t.Errorf("Repeated scopes should be kept once, got %v", created.Scopes)
+	}
+
+	stored := repo.Keys[0]
+	if stored.Hash != utils.HashToken(created.Key) || strings.Contains(stored.Hash, created.Key) {
+		t.Error("Only the hash of the key should be stored")
+	}
+
+	keys, err := service.SelectUserAPIKeys(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Name != "ci" {
+		t.Fatalf("User should have the 'ci' key, got %v", keys)
+	}
+
+	if err := service.RevokeAPIKey(2, created.ID); err == nil || err.Code() != http.StatusNotFound {
+		t.Errorf("Keys of other users should not be found, got %v", err)
+	}
+	if err := service.RevokeAPIKey(1, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, _ = service.SelectUserAPIKeys(1)
+	if len(keys) != 0 {
+		t.Errorf("Revoked keys should not be listed, got %v", keys)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// DefaultLockoutService implements LockoutService
+var _ interfaces.LockoutService = (*DefaultLockoutService)(nil)
+
+const (
+	defaultLockoutAccountThreshold = 5
+	defaultLockoutIPThreshold      = 50
+	defaultLockoutWindow           = 15 * time.Minute
+	defaultLockoutDuration         = time.Minute
+	defaultLockoutMaxDuration      = 24 * time.Hour
+)
+
+type DefaultLockoutService struct {
+	repo     interfaces.LoginLockoutRepository
+	settings *config.Settings
+	now      func() time.Time
+}
+
+func NewDefaultLockoutService(
+	loginLockoutRepository interfaces.LoginLockoutRepository,
+	settings *config.Settings,
+) interfaces.LockoutService {
+	return &DefaultLockoutService{
+		repo:     loginLockoutRepository,
+		settings: settings,
+		now:      time.Now,
+	}
+}
+
+// CheckLogin fails with 429 while the account or the client IP is locked
+// out. Accounts are keyed by email whether they exist or not, so lockouts
+// don't tell which emails have an account.
+func (s DefaultLockoutService) CheckLogin(email string, clientIP string) *utils.ErrorCode {
+	now := s.now().UTC()
+
+	for kind, subject := range lockoutSubjects(email, clientIP) {
+		lockout, errC := s.repo.SelectLoginLockout(kind, subject)
+		if errC != nil {
+			if errC.Code() == http.StatusNotFound {
+				continue
+			}
+			return errC
+		}
+
+		if lockout.LockedUntil != nil && now.Before(*lockout.LockedUntil) {
+			return utils.NewErrorCodeString(http.StatusTooManyRequests, "Too many failed login attempts, try again later")
+		}
+	}
+
+	return nil
+}
+
+// RecordFailedLogin counts a failed login of the account and client IP,
+// locking them out once past their threshold.
+func (s DefaultLockoutService) RecordFailedLogin(email string, clientIP string) *utils.ErrorCode {
+	thresholds := map[string]int{
+		models.LockoutKindAccount: s.accountThreshold(),
+		models.LockoutKindIP:      s.ipThreshold(),
+	}
+
+	for kind, subject := range lockoutSubjects(email, clientIP) {
+		errC := s.recordFailure(kind, subject, thresholds[kind])
+		if errC != nil {
+			return errC
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccessfulLogin forgets the failed logins of the account. Those of the
+// client IP are kept, or logging into an own account would reset them.
+func (s DefaultLockoutService) RecordSuccessfulLogin(email string) *utils.ErrorCode {
+	errC := s.repo.RemoveLoginLockout(models.LockoutKindAccount, normalizeLockoutEmail(email))
+	if errC != nil && errC.Code() != http.StatusNotFound {
+		return errC
+	}
+
+	return nil
+}
+
+func (s DefaultLockoutService) SelectActiveLockouts() ([]*dtos.LoginLockout, *utils.ErrorCode) {
+	return s.repo.SelectActiveLoginLockouts(s.now().UTC())
+}
+
+func (s DefaultLockoutService) ClearLockout(kind string, subject string) *utils.ErrorCode {
+	switch kind {
+	case models.LockoutKindAccount:
+		subject = normalizeLockoutEmail(subject)
+	case models.LockoutKindIP:
+	default:
+		return utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Unknown lockout kind '%s'", kind))
+	}
+
+	return s.repo.RemoveLoginLockout(kind, subject)
+}
+
+// recordFailure counts a failed login of the subject. The count is updated by
+// the repository, so failed logins racing each other aren't lost.
+func (s DefaultLockoutService) recordFailure(kind string, subject string, threshold int) *utils.ErrorCode {
+	now := s.now().UTC()
+
+	return s.repo.UpdateLoginLockout(kind, subject, now, func(lockout *dtos.LoginLockout) {
+		stillLocked := lockout.LockedUntil != nil && now.Before(*lockout.LockedUntil)
+		if !stillLocked && now.Sub(lockout.LastFailureAt) > s.window() {
+			lockout.Failures = 0
+			lockout.LockedUntil = nil
+		}
+
+		lockout.Failures++
+		lockout.LastFailureAt = now
+
+		if lockout.Failures >= threshold {
+			lockedUntil := now.Add(s.lockoutDuration(lockout.Failures - threshold))
+			lockout.LockedUntil = &lockedUntil
+		}
+	})
+}
+
+// lockoutDuration doubles the base duration for each failure past the
+// threshold, up to the max duration.
+func (s DefaultLockoutService) lockoutDuration(failuresPastThreshold int) time.Duration {
+	duration := s.settings.Auth.Lockout.Duration
+	if duration <= 0 {
+		duration = defaultLockoutDuration
+	}
+
+	maxDuration := s.settings.Auth.Lockout.MaxDuration
+	if maxDuration <= 0 {
+		maxDuration = defaultLockoutMaxDuration
+	}
+
+	for i := 0; i < failuresPastThreshold && duration < maxDuration; i++ {
+		duration *= 2
+	}
+
+	if duration > maxDuration {
+		return maxDuration
+	}
+
+	return duration
+}
+
+func (s DefaultLockoutService) accountThreshold() int {
+	if s.settings.Auth.Lockout.AccountThreshold > 0 {
+		return s.settings.Auth.Lockout.AccountThreshold
+	}
+
+	return defaultLockoutAccountThreshold
+}
+
+func (s DefaultLockoutService) ipThreshold() int {
+	if s.settings.Auth.Lockout.IPThreshold > 0 {
+		return s.settings.Auth.Lockout.IPThreshold
+	}
+
+	return defaultLockoutIPThreshold
+}
+
+func (s DefaultLockoutService) window() time.Duration {
+	if s.settings.Auth.Lockout.Window > 0 {
+		return s.settings.Auth.Lockout.Window
+	}
+
+	return defaultLockoutWindow
+}
+
+// lockoutSubjects returns the subject of each lockout kind, skipping the
+// client IP when unknown.
+func lockoutSubjects(email string, clientIP string) map[string]string {
+	subjects := map[string]string{
+		models.LockoutKindAccount: normalizeLockoutEmail(email),
+	}
+
+	if clientIP != "" {
+		subjects[models.LockoutKindIP] = clientIP
+	}
+
+	return subjects
+}
+
+func normalizeLockoutEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+)
+
+func newTestLockoutService(now *time.Time) (*DefaultLockoutService, *mocks.MockedLoginLockoutRepository) {
+	settings := &config.Settings{
+		Auth: config.Auth{
+			Lockout: config.Lockout{
+				AccountThreshold: 3,
+				IPThreshold:      5,
+				Window:           15 * time.Minute,
+				Duration:         time.Minute,
+				MaxDuration:      5 * time.Minute,
+			},
+		},
+	}
+
+	repo := mocks.NewMockedLoginLockoutRepository()
+
+	service := NewDefaultLockoutService(repo, settings).(*DefaultLockoutService)
+	service.now = func() time.Time { return *now }
+
+	return service, repo
+}
+
+func TestLockout_Account(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _ := newTestLockoutService(&now)
+
+	for i := 0; i < 2; i++ {
+		if err := service.RecordFailedLogin("diego@mail.com", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := service.CheckLogin("diego@mail.com", "10.0.0.1"); err != nil {
+		t.Fatalf("Failures under the threshold should not lock out, got %v", err)
+	}
+
+	if err := service.RecordFailedLogin("Diego@Mail.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := service.CheckLogin("diego@mail.com", "10.0.0.2")
+	if err == nil || err.Code() != http.StatusTooManyRequests {
+		t.Fatalf("Account should be locked out with 429 from any IP, got %v", err)
+	}
+
+	if err := service.CheckLogin("alex@mail.com", "10.0.0.1"); err != nil {
+		t.Errorf("Other accounts from the same IP should not be locked out, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+
+	if err := service.CheckLogin("diego@mail.com", "10.0.0.1"); err != nil {
+		t.Fatalf("Lockout should end after its duration, got %v", err)
+	}
+
+	// Failing again past the threshold doubles the lockout
+	if err := service.RecordFailedLogin("diego@mail.com", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute)
+
+	err = service.CheckLogin("diego@mail.com", "10.0.0.1")
+	if err == nil || err.Code() != http.StatusTooManyRequests {
+		t.Fatalf("Lockout should have doubled, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+
+	if err := service.CheckLogin("diego@mail.com", "10.0.0.1"); err != nil {
+		t.Fatalf("Doubled lockout should end after twice the duration, got %v", err)
+	}
+
+	if err := service.RecordSuccessfulLogin("diego@mail.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RecordFailedLogin("diego@mail.com", "10.0.0.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.CheckLogin("diego@mail.com", "10.0.0.3"); err != nil {
+		t.Errorf("Successful login should reset the account failures, got %v", err)
+	}
+}
+
+func TestLockout_MaxDuration(t *testing.T) {
+	testCases := []struct {
+		failuresPastThreshold int
+		duration              time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{2, 4 * time.Minute},
+		{3, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _ := newTestLockoutService(&now)
+
+	for _, _case := range testCases {
+		duration := service.lockoutDuration(_case.failuresPastThreshold)
+
+		if duration != _case.duration {
+			t.Errorf("Lockout after %d failures past the threshold should be '%s', got '%s'", _case.failuresPastThreshold, _case.duration, duration)
+		}
+	}
+}
+
+func TestLockout_IP(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, repo := newTestLockoutService(&now)
+
+	emails := []string{"a@mail.com", "b@mail.com", "c@mail.com", "d@mail.com", "e@mail.com"}
+	for _, email := range emails {
+		if err := service.RecordFailedLogin(email, "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := service.CheckLogin("diego@mail.com", "10.0.0.1")
+	if err == nil || err.Code() != http.StatusTooManyRequests {
+		t.Fatalf("IP should be locked out with 429 for any account, got %v", err)
+	}
+
+	if err := service.CheckLogin("diego@mail.com", "10.0.0.2"); err != nil {
+		t.Errorf("Other IPs should not be locked out, got %v", err)
+	}
+
+	lockouts, err := service.SelectActiveLockouts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lockouts) != 1 || lockouts[0].Kind != models.LockoutKindIP {
+		t.Fatalf("Only the IP should be locked out, got %v", lockouts)
+	}
+
+	if err := service.ClearLockout(models.LockoutKindIP, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.CheckLogin("diego@mail.com", "10.0.0.1"); err != nil {
+		t.Errorf("Cleared lockout should allow logins, got %v", err)
+	}
+	if _, ok := repo.Lockouts[models.LockoutKindIP+":10.0.0.1"]; ok {
+		t.Error("Cleared lockout should be removed")
+	}
+
+	err = service.ClearLockout("user", "10.0.0.1")
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Unknown lockout kind should be rejected with 400, got %v", err)
+	}
+}
+
+func TestLockout_WindowExpires(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _ := newTestLockoutService(&now)
+
+	for i := 0; i < 2; i++ {
+		if err := service.RecordFailedLogin("diego@mail.com", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = now.Add(16 * time.Minute)
+
+	if err := service.RecordFailedLogin("diego@mail.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.CheckLogin("diego@mail.com", ""); err != nil {
+		t.Errorf("Failures older than the window should not count, got %v", err)
+	}
+}
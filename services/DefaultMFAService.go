@@ -0,0 +1,308 @@
+package services
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/mfa"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// DefaultMFAService implements MFAService
+var _ interfaces.MFAService = (*DefaultMFAService)(nil)
+
+const (
+	defaultMFATokenLifetime = 5 * time.Minute
+	defaultTOTPIssuer       = "simple-api"
+)
+
+type DefaultMFAService struct {
+	repo     interfaces.MFARepository
+	userRepo interfaces.UserRepository
+	history  interfaces.LoginHistoryRepository
+	auth     interfaces.Authenticator
+	tokens   interfaces.TokenService
+	lockouts interfaces.LockoutService
+	settings *config.Settings
+	now      func() time.Time
+}
+
+func NewDefaultMFAService(
+	mfaRepository interfaces.MFARepository,
+	userRepository interfaces.UserRepository,
+	loginHistoryRepository interfaces.LoginHistoryRepository,
+	authenticator interfaces.Authenticator,
+	tokenService interfaces.TokenService,
+	lockoutService interfaces.LockoutService,
+	settings *config.Settings,
+) interfaces.MFAService {
+	return &DefaultMFAService{
+		repo:     mfaRepository,
+		userRepo: userRepository,
+		history:  loginHistoryRepository,
+		auth:     authenticator,
+		tokens:   tokenService,
+		lockouts: lockoutService,
+		settings: settings,
+		now:      time.Now,
+	}
+}
+
+// EnrollTOTP starts a TOTP enrolment, returning the secret to be added to an
+// authenticator app. It's only enabled after ConfirmTOTP.
+func (s DefaultMFAService) EnrollTOTP(id int) (*dtos.TOTPEnrolment, *utils.ErrorCode) {
+	enabled, errC := s.IsTOTPEnabled(id)
+	if errC != nil {
+		return nil, errC
+	}
+	if enabled {
+		return nil, utils.NewErrorCodeString(http.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	user, errC := s.userRepo.SelectUserFromId(id)
+	if errC != nil {
+		return nil, errC
+	}
+
+	secret, err := mfa.NewTOTPSecret()
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	errC = s.repo.SaveUserTOTP(id, secret)
+	if errC != nil {
+		return nil, errC
+	}
+
+	return &dtos.TOTPEnrolment{
+		Secret: secret,
+		URI:    mfa.TOTPURI(s.totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the pending TOTP enrolment when code matches its secret,
+// returning new recovery codes. They are only stored hashed, so this is the
+// only time they are shown.
+func (s DefaultMFAService) ConfirmTOTP(id int, code string) (*dtos.RecoveryCodes, *utils.ErrorCode) {
+	totp, errC := s.repo.SelectUserTOTP(id)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, utils.NewErrorCodeString(http.StatusConflict, "Two-factor enrolment was not started")
+		}
+		return nil, errC
+	}
+
+	if totp.ConfirmedAt != nil {
+		return nil, utils.NewErrorCodeString(http.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	ok, errC := s.useTOTPCode(totp, code)
+	if errC != nil {
+		return nil, errC
+	}
+	if !ok {
+		return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid two-factor code")
+	}
+
+	codes, err := mfa.NewRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(mfa.NormalizeRecoveryCode(code)))
+	}
+
+	errC = s.repo.ReplaceRecoveryCodes(id, hashes)
+	if errC != nil {
+		return nil, errC
+	}
+
+	errC = s.repo.ConfirmUserTOTP(id, s.now().UTC())
+	if errC != nil {
+		return nil, errC
+	}
+
+	return &dtos.RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP removes the TOTP enrolment and recovery codes of the user,
+// requiring a last valid code.
+func (s DefaultMFAService) DisableTOTP(id int, code string) *utils.ErrorCode {
+	enabled, errC := s.IsTOTPEnabled(id)
+	if errC != nil {
+		return errC
+	}
+	if !enabled {
+		return utils.NewErrorCodeString(http.StatusConflict, "Two-factor authentication is not enabled")
+	}
+
+	ok, errC := s.verifyCode(id, code)
+	if errC != nil {
+		return errC
+	}
+	if !ok {
+		return utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid two-factor code")
+	}
+
+	return s.repo.RemoveUserTOTP(id)
+}
+
+func (s DefaultMFAService) IsTOTPEnabled(id int) (bool, *utils.ErrorCode) {
+	totp, errC := s.repo.SelectUserTOTP(id)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errC
+	}
+
+	return totp.ConfirmedAt != nil, nil
+}
+
+// IssueMFAToken returns a short-lived token proving the user password was
+// right, to be exchanged with CompleteLogin.
+func (s DefaultMFAService) IssueMFAToken(user *dtos.IdentifiedUser) (*dtos.TokenResponse, *utils.ErrorCode) {
+	lifetime := s.settings.Auth.MFATokenLifetime
+	if lifetime <= 0 {
+		lifetime = defaultMFATokenLifetime
+	}
+
+	mfaToken, err := s.auth.GenerateToken(&dtos.TokenClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Scopes:    []string{models.ScopeMFAPending},
+		ExpiresAt: s.now().UTC().Add(lifetime),
+	})
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return &dtos.TokenResponse{MFAToken: mfaToken}, nil
+}
+
+// CompleteLogin exchanges an MFA token and a valid TOTP or recovery code for
+// the user tokens. The MFA token is revoked, so it works only once.
+//
+// Wrong codes count towards the lockout of the account and client IP.
+func (s DefaultMFAService) CompleteLogin(request *dtos.MFALoginRequest) (*dtos.TokenResponse, *utils.ErrorCode) {
+	claims, err := s.auth.ParseToken(request.MFAToken)
+	if err != nil {
+		if !utils.IsTokenError(err) {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+		return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid MFA token")
+	}
+
+	if !claims.HasScope(models.ScopeMFAPending) {
+		return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid MFA token")
+	}
+
+	errC := s.lockouts.CheckLogin(claims.Email, request.ClientIP)
+	if errC != nil {
+		if errC.Code() == http.StatusTooManyRequests {
+			s.recordLogin(request, claims, false, models.LoginReasonLockedOut)
+		}
+		return nil, errC
+	}
+
+	ok, errC := s.verifyCode(claims.UserID, request.Code)
+	if errC != nil {
+		return nil, errC
+	}
+	if !ok {
+		s.recordLogin(request, claims, false, models.LoginReasonInvalidMFACode)
+
+		errC = s.lockouts.RecordFailedLogin(claims.Email, request.ClientIP)
+		if errC != nil {
+			return nil, errC
+		}
+		return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid two-factor code")
+	}
+
+	errC = s.lockouts.RecordSuccessfulLogin(claims.Email)
+	if errC != nil {
+		return nil, errC
+	}
+
+	err = s.auth.RevokeToken(claims)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	user, errC := s.userRepo.SelectUserFromId(claims.UserID)
+	if errC != nil {
+		return nil, errC
+	}
+
+	tokens, errC := s.tokens.IssueTokens(user, &dtos.ClientInfo{IP: request.ClientIP, UserAgent: request.UserAgent})
+	if errC != nil {
+		return nil, errC
+	}
+
+	s.recordLogin(request, claims, true, models.LoginReasonMFA)
+
+	return tokens, nil
+}
+
+// recordLogin appends the second factor login to the history of the user.
+func (s DefaultMFAService) recordLogin(request *dtos.MFALoginRequest, claims *dtos.TokenClaims, succeeded bool, reason string) {
+	recordLoginAttempt(s.history, s.userRepo, &dtos.LoginAttempt{
+		UserID:    &claims.UserID,
+		Email:     claims.Email,
+		IP:        request.ClientIP,
+		UserAgent: request.UserAgent,
+		Succeeded: succeeded,
+		Reason:    reason,
+		CreatedAt: s.now().UTC(),
+	})
+}
+
+// verifyCode accepts a TOTP code of the enabled enrolment, or an unused
+// recovery code which gets used up.
+func (s DefaultMFAService) verifyCode(id int, code string) (bool, *utils.ErrorCode) {
+	if len(code) != mfa.TOTPDigits {
+		return s.repo.UseRecoveryCode(id, utils.HashToken(mfa.NormalizeRecoveryCode(code)), s.now().UTC())
+	}
+
+	totp, errC := s.repo.SelectUserTOTP(id)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errC
+	}
+
+	if totp.ConfirmedAt == nil {
+		return false, nil
+	}
+
+	return s.useTOTPCode(totp, code)
+}
+
+// useTOTPCode checks the code and marks its time step as used, refusing
+// replays of a code within its validity window.
+func (s DefaultMFAService) useTOTPCode(totp *dtos.UserTOTP, code string) (bool, *utils.ErrorCode) {
+	step, ok, err := mfa.ValidateTOTP(totp.Secret, code, s.now())
+	if err != nil {
+		return false, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	return s.repo.UseTOTPStep(totp.UserID, step)
+}
+
+func (s DefaultMFAService) totpIssuer() string {
+	if s.settings.Auth.Issuer != "" {
+		return s.settings.Auth.Issuer
+	}
+
+	return defaultTOTPIssuer
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/hashing"
+	"github.com/d1360-64rc14/simple-api/mfa"
+	"github.com/d1360-64rc14/simple-api/mocks"
+)
+
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	code, err := mfa.TOTPCode(secret, mfa.TOTPStep(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
+
+func TestMFA(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	userService := newTestUserService(t, hashing.AlgorithmBCrypt, mocks.NewMockedUserRepository())
+	service := userService.mfa.(*DefaultMFAService)
+	service.now = func() time.Time { return now }
+
+	user := createTestUser(t, userService, "diego@mail.com", "myPassword!")
+	login := &dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!"}
+
+	enrolment, err := service.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enrolment.URI, "otpauth://totp/simple-api:diego@mail.com?") {
+		t.Errorf("Unexpected enrolment URI '%s'", enrolment.URI)
+	}
+
+	_, err = service.ConfirmTOTP(user.ID, "000000")
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Fatalf("Wrong code should be rejected with 401, got %v", err)
+	}
+
+	tokens, err := userService.LoginUser(login)
+	if err != nil || tokens.Token == "" {
+		t.Fatalf("Unconfirmed enrolment should not require a code, got %v", err)
+	}
+
+	codes, err := service.ConfirmTOTP(user.ID, totpCode(t, enrolment.Secret, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes.RecoveryCodes) != mfa.RecoveryCodeCount {
+		t.Fatalf("Should get %d recovery codes, got %d", mfa.RecoveryCodeCount, len(codes.RecoveryCodes))
+	}
+
+	if _, err := service.EnrollTOTP(user.ID); err == nil || err.Code() != http.StatusConflict {
+		t.Errorf("Enrolling twice should be rejected with 409, got %v", err)
+	}
+
+	tokens, err = userService.LoginUser(login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.MFAToken == "" || tokens.Token != "" || tokens.RefreshToken != "" {
+		t.Fatalf("Login should only return an MFA token, got %+v", tokens)
+	}
+	mfaToken := tokens.MFAToken
+
+	_, err = service.CompleteLogin(&dtos.MFALoginRequest{MFAToken: mfaToken, MFACode: dtos.MFACode{Code: totpCode(t, enrolment.Secret, now)}})
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Errorf("Code already used for confirmation should be rejected with 401, got %v", err)
+	}
+
+	now = now.Add(mfa.TOTPPeriod)
+
+	tokens, err = service.CompleteLogin(&dtos.MFALoginRequest{MFAToken: mfaToken, MFACode: dtos.MFACode{Code: totpCode(t, enrolment.Secret, now)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.Token == "" || tokens.RefreshToken == "" {
+		t.Error("Completed login should return both tokens")
+	}
+
+	now = now.Add(mfa.TOTPPeriod)
+
+	_, err = service.CompleteLogin(&dtos.MFALoginRequest{MFAToken: mfaToken, MFACode: dtos.MFACode{Code: totpCode(t, enrolment.Secret, now)}})
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Errorf("MFA token should be single-use, got %v", err)
+	}
+
+	recoveryCode := dtos.MFACode{Code: strings.ToUpper(strings.ReplaceAll(codes.RecoveryCodes[0], "-", ""))}
+
+	for i, expectedCode := range []int{http.StatusOK, http.StatusUnauthorized} {
+		tokens, err = userService.LoginUser(login)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = service.CompleteLogin(&dtos.MFALoginRequest{MFAToken: tokens.MFAToken, MFACode: recoveryCode})
+		if (expectedCode == http.StatusOK) != (err == nil) || (err != nil && err.Code() != expectedCode) {
+			t.Errorf("Recovery code use %d should respond '%d', got '%v'", i, expectedCode, err)
+		}
+	}
+
+	if err := service.DisableTOTP(user.ID, codes.RecoveryCodes[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err = userService.LoginUser(login)
+	if err != nil || tokens.Token == "" {
+		t.Errorf("Disabled two-factor authentication should not require a code, got %v", err)
+	}
+}
+
+func TestMFA_Lockout(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	userService := newTestUserService(t, hashing.AlgorithmBCrypt, mocks.NewMockedUserRepository())
+	service := userService.mfa.(*DefaultMFAService)
+	service.now = func() time.Time { return now }
+
+	user := createTestUser(t, userService, "diego@mail.com", "myPassword!")
+	login := &dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!", ClientIP: "10.0.0.1"}
+
+	enrolment, err := service.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ConfirmTOTP(user.ID, totpCode(t, enrolment.Secret, now)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Right passwords must not forget the wrong codes sent in between
+	for i := 0; i < defaultLockoutAccountThreshold; i++ {
+		tokens, err := userService.LoginUser(login)
+		if err != nil {
+			t.Fatalf("Login %d should return an MFA token, got %v", i, err)
+		}
+
+		_, err = service.CompleteLogin(&dtos.MFALoginRequest{MFAToken: tokens.MFAToken, MFACode: dtos.MFACode{Code: "000000"}, ClientIP: "10.0.0.1"})
+		if err == nil || err.Code() != http.StatusUnauthorized {
+			t.Fatalf("Wrong code should be rejected with 401, got %v", err)
+		}
+	}
+
+	_, err = userService.LoginUser(login)
+	if err == nil || err.Code() != http.StatusTooManyRequests {
+		t.Errorf("Account should be locked out after %d wrong codes, got %v", defaultLockoutAccountThreshold, err)
+	}
+}
@@ -0,0 +1,553 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// DefaultOAuthService implements OAuthService
+var _ interfaces.OAuthService = (*DefaultOAuthService)(nil)
+
+const (
+	defaultAuthorizationCodeLifetime = time.Minute
+	oauthTokenType                   = "Bearer"
+)
+
+// DefaultOAuthService is the OAuth 2.0 authorization server of the
+// first-party apps, issuing the same access tokens as logins do, and their
+// OpenID Connect provider.
+//
+// Errors of the OAuth endpoints carry messages formatted as
+// "<error code>: <description>", see dtos.NewOAuthError.
+type DefaultOAuthService struct {
+	repo     interfaces.OAuthRepository
+	userRepo interfaces.UserRepository
+	auth     interfaces.Authenticator
+	settings *config.Settings
+	now      func() time.Time
+}
+
+func NewDefaultOAuthService(
+	oauthRepository interfaces.OAuthRepository,
+	userRepository interfaces.UserRepository,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.OAuthService {
+	return &DefaultOAuthService{
+		repo:     oauthRepository,
+		userRepo: userRepository,
+		auth:     authenticator,
+		settings: settings,
+		now:      time.Now,
+	}
+}
+
+// RegisterClient registers an OAuth client, returning the secret of
+// confidential clients for the only time.
+//
+// Clients using the authorization code grant need absolute redirect URIs,
+// and only confidential clients may use the client credentials grant. Scopes
+// are permissions or OpenID Connect scopes.
+func (s DefaultOAuthService) RegisterClient(request *dtos.OAuthClientRequest) (*dtos.CreatedOAuthClient, *utils.ErrorCode) {
+	grantTypes := make([]string, 0, len(request.GrantTypes))
+	for _, grantType := range request.GrantTypes {
+		if grantType != models.GrantAuthorizationCode && grantType != models.GrantClientCredentials {
+			return nil, utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Unsupported grant type '%s'", grantType))
+		}
+		if !containsString(grantTypes, grantType) {
+			grantTypes = append(grantTypes, grantType)
+		}
+	}
+
+	if request.Public && containsString(grantTypes, models.GrantClientCredentials) {
+		return nil, utils.NewErrorCodeString(http.StatusBadRequest, "Public clients cannot use the client credentials grant")
+	}
+
+	if containsString(grantTypes, models.GrantAuthorizationCode) && len(request.RedirectURIs) == 0 {
+		return nil, utils.NewErrorCodeString(http.StatusBadRequest, "Clients using the authorization code grant need a redirect URI")
+	}
+
+	for _, redirectURI := range request.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Host == "" || parsed.Fragment != "" {
+			return nil, utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Redirect URI '%s' should be absolute and without fragment", redirectURI))
+		}
+	}
+
+	scopes := make([]string, 0, len(request.Scopes))
+	for _, scope := range request.Scopes {
+		if !authorization.IsKnownPermission(scope) && !models.IsOIDCScope(scope) {
+			return nil, utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Unknown scope '%s'", scope))
+		}
+		if !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	clientId, err := utils.NewRandomToken(16)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	client := &dtos.OAuthClient{
+		ID:           clientId,
+		Name:         request.Name,
+		Public:       request.Public,
+		RedirectURIs: request.RedirectURIs,
+		GrantTypes:   grantTypes,
+		Scopes:       scopes,
+		CreatedAt:    s.now().UTC(),
+	}
+
+	var secret string
+	if !client.Public {
+		secret, err = utils.NewRandomToken(32)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	errC := s.repo.CreateOAuthClient(client)
+	if errC != nil {
+		return nil, errC
+	}
+
+	return &dtos.CreatedOAuthClient{
+		OAuthClient:  *client,
+		ClientSecret: secret,
+	}, nil
+}
+
+func (s DefaultOAuthService) SelectClients() ([]*dtos.OAuthClient, *utils.ErrorCode) {
+	return s.repo.SelectOAuthClients()
+}
+
+func (s DefaultOAuthService) RemoveClient(clientId string) *utils.ErrorCode {
+	return s.repo.RemoveOAuthClient(clientId)
+}
+
+// Authorize issues an authorization code to the client for the user of the
+// claims, returning the redirect URI carrying it. First-party apps are
+// trusted, so the user isn't asked for consent.
+//
+// Requests must carry a S256 PKCE code challenge, and tokens of API keys or
+// OAuth clients can't authorize further clients.
+func (s DefaultOAuthService) Authorize(claims *dtos.TokenClaims, request *dtos.OAuthAuthorizeRequest) (*dtos.OAuthAuthorization, *utils.ErrorCode) {
+	if claims.IsDelegated() {
+		return nil, oauthError(http.StatusForbidden, models.OAuthAccessDenied, "API keys and OAuth tokens cannot authorize clients")
+	}
+
+	if request.ResponseType != models.ResponseTypeCode {
+		return nil, oauthError(http.StatusBadRequest, models.OAuthUnsupportedResponseType, "response_type must be 'code'")
+	}
+
+	client, errC := s.repo.SelectOAuthClient(request.ClientID)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, oauthError(http.StatusBadRequest, models.OAuthInvalidRequest, "Unknown client")
+		}
+		return nil, errC
+	}
+
+	if !containsString(client.GrantTypes, models.GrantAuthorizationCode) {
+		return nil, oauthError(http.StatusBadRequest, models.OAuthUnauthorizedClient, "Client cannot use the authorization code grant")
+	}
+
+	redirectURI := request.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !containsString(client.RedirectURIs, redirectURI) {
+		return nil, oauthError(http.StatusBadRequest, models.OAuthInvalidRequest, "redirect_uri is not registered for the client")
+	}
+
+	if request.CodeChallengeMethod != models.CodeChallengeS256 {
+		return nil, oauthError(http.StatusBadRequest, models.OAuthInvalidRequest, "code_challenge_method must be 'S256'")
+	}
+
+	scopes, errC := grantedScopes(client, request.Scope)
+	if errC != nil {
+		return nil, errC
+	}
+
+	code, err := utils.NewRandomToken(32)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	errC = s.repo.CreateAuthorizationCode(&dtos.AuthorizationCode{
+		Hash:          utils.HashToken(code),
+		ClientID:      client.ID,
+		UserID:        claims.UserID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		CodeChallenge: request.CodeChallenge,
+		Nonce:         request.Nonce,
+		ExpiresAt:     s.now().UTC().Add(s.authorizationCodeLifetime()),
+	})
+	if errC != nil {
+		return nil, errC
+	}
+
+	redirectTo, err := url.Parse(redirectURI)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	query := redirectTo.Query()
+	query.Set("code", code)
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+	redirectTo.RawQuery = query.Encode()
+
+	return &dtos.OAuthAuthorization{
+		RedirectTo: redirectTo.String(),
+	}, nil
+}
+
+// Token authenticates the client and exchanges its grant for an access token.
+//
+// Authorization codes give tokens for their user, restricted to the granted
+// scopes, along with an ID token when the "openid" scope was granted. Client
+// credentials give tokens for the client itself, which has no roles of its
+// own.
+func (s DefaultOAuthService) Token(request *dtos.OAuthTokenRequest) (*dtos.OAuthTokenResponse, *utils.ErrorCode) {
+	client, errC := s.authenticateClient(request.ClientID, request.ClientSecret)
+	if errC != nil {
+		return nil, errC
+	}
+
+	if request.GrantType != models.GrantAuthorizationCode && request.GrantType != models.GrantClientCredentials {
+		return nil, oauthError(http.StatusBadRequest, models.OAuthUnsupportedGrantType, fmt.Sprintf("Unsupported grant_type '%s'", request.GrantType))
+	}
+
+	if !containsString(client.GrantTypes, request.GrantType) {
+		return nil, oauthError(http.StatusBadRequest, models.OAuthUnauthorizedClient, fmt.Sprintf("Client cannot use the %s grant", request.GrantType))
+	}
+
+	var claims *dtos.TokenClaims
+	var idToken *dtos.IDToken
+	if request.GrantType == models.GrantAuthorizationCode {
+		claims, idToken, errC = s.exchangeAuthorizationCode(client, request)
+	} else {
+		claims, errC = s.clientCredentials(client, request)
+	}
+	if errC != nil {
+		return nil, errC
+	}
+
+	accessToken, err := s.auth.GenerateToken(claims)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	response := &dtos.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   oauthTokenType,
+		ExpiresIn:   int64(claims.ExpiresAt.Sub(claims.IssuedAt).Seconds()),
+		Scope:       strings.Join(claims.Scopes, " "),
+	}
+
+	if idToken != nil {
+		response.IDToken, err = s.auth.GenerateIDToken(idToken)
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+	}
+
+	return response, nil
+}
+
+// Introspect describes any token this API accepts to a confidential client,
+// as in RFC 7662.
+func (s DefaultOAuthService) Introspect(request *dtos.OAuthTokenLookup) (*dtos.OAuthIntrospection, *utils.ErrorCode) {
+	client, errC := s.authenticateClient(request.ClientID, request.ClientSecret)
+	if errC != nil {
+		return nil, errC
+	}
+
+	if client.Public {
+		return nil, oauthError(http.StatusUnauthorized, models.OAuthInvalidClient, "Public clients cannot introspect tokens")
+	}
+
+	claims, err := s.auth.ParseToken(request.Token)
+	if err != nil {
+		if !utils.IsTokenError(err) {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		return &dtos.OAuthIntrospection{Active: false}, nil
+	}
+
+	if claims.HasScope(models.ScopeMFAPending) {
+		return &dtos.OAuthIntrospection{Active: false}, nil
+	}
+
+	introspection := &dtos.OAuthIntrospection{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientID:  claims.ClientID,
+		Username:  claims.Email,
+		TokenType: oauthTokenType,
+		Subject:   strconv.Itoa(claims.UserID),
+		TokenID:   claims.TokenID,
+	}
+	if claims.IsClient() {
+		introspection.Subject = claims.ClientID
+	}
+	if !claims.ExpiresAt.IsZero() {
+		introspection.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if !claims.IssuedAt.IsZero() {
+		introspection.IssuedAt = claims.IssuedAt.Unix()
+	}
+
+	return introspection, nil
+}
+
+// Revoke revokes an access token issued to the client, as in RFC 7009.
+// Tokens already invalid are ignored.
+func (s DefaultOAuthService) Revoke(request *dtos.OAuthTokenLookup) *utils.ErrorCode {
+	client, errC := s.authenticateClient(request.ClientID, request.ClientSecret)
+	if errC != nil {
+		return errC
+	}
+
+	claims, err := s.auth.ParseToken(request.Token)
+	if err != nil {
+		if !utils.IsTokenError(err) {
+			return utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+
+		return nil
+	}
+
+	if claims.ClientID != client.ID {
+		return oauthError(http.StatusBadRequest, models.OAuthUnauthorizedClient, "Token was not issued to the client")
+	}
+
+	err = s.auth.RevokeToken(claims)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// UserInfo returns the OpenID Connect claims of the user of the claims.
+//
+// Scoped OAuth tokens need the "openid" scope, and only get the claims their
+// scopes grant. Tokens of clients acting for themselves have no user.
+func (s DefaultOAuthService) UserInfo(claims *dtos.TokenClaims) (*dtos.OIDCClaims, *utils.ErrorCode) {
+	if claims.IsClient() {
+		return nil, oauthError(http.StatusForbidden, models.OAuthInsufficientScope, "Token was issued to a client, not a user")
+	}
+
+	var scopes []string
+	if claims.IsDelegated() && len(claims.Scopes) > 0 {
+		if !claims.HasScope(models.ScopeOpenID) {
+			return nil, oauthError(http.StatusForbidden, models.OAuthInsufficientScope, "Token lacks the 'openid' scope")
+		}
+
+		scopes = claims.Scopes
+	}
+
+	user, errC := s.userRepo.SelectUserFromId(claims.UserID)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, oauthError(http.StatusUnauthorized, models.OAuthInvalidToken, "User no longer exists")
+		}
+		return nil, errC
+	}
+
+	return newOIDCClaims(user, scopes), nil
+}
+
+// authenticateClient checks the secret of confidential clients, public ones
+// only having to identify themselves.
+func (s DefaultOAuthService) authenticateClient(clientId string, clientSecret string) (*dtos.OAuthClient, *utils.ErrorCode) {
+	if clientId == "" {
+		return nil, oauthError(http.StatusUnauthorized, models.OAuthInvalidClient, "Missing client authentication")
+	}
+
+	client, errC := s.repo.SelectOAuthClient(clientId)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, oauthError(http.StatusUnauthorized, models.OAuthInvalidClient, "Invalid client credentials")
+		}
+		return nil, errC
+	}
+
+	if client.Public {
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError(http.StatusUnauthorized, models.OAuthInvalidClient, "Invalid client credentials")
+	}
+
+	return client, nil
+}
+
+// exchangeAuthorizationCode uses up the authorization code, returning the
+// claims of its user, and those of their ID token when the "openid" scope was
+// granted.
+func (s DefaultOAuthService) exchangeAuthorizationCode(client *dtos.OAuthClient, request *dtos.OAuthTokenRequest) (*dtos.TokenClaims, *dtos.IDToken, *utils.ErrorCode) {
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidRequest, "code and code_verifier are required")
+	}
+
+	stored, errC := s.repo.SelectAuthorizationCodeFromHash(utils.HashToken(request.Code))
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "Invalid authorization code")
+		}
+		return nil, nil, errC
+	}
+
+	now := s.now().UTC()
+
+	if stored.ClientID != client.ID {
+		return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "Invalid authorization code")
+	}
+	if stored.UsedAt != nil {
+		return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "Authorization code was already used")
+	}
+	if !now.Before(stored.ExpiresAt) {
+		return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "Authorization code expired")
+	}
+	if request.RedirectURI != "" && request.RedirectURI != stored.RedirectURI {
+		return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "redirect_uri differs from the authorization request")
+	}
+	if !verifyCodeChallenge(request.CodeVerifier, stored.CodeChallenge) {
+		return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "code_verifier doesn't match the code challenge")
+	}
+
+	used, errC := s.repo.UseAuthorizationCode(stored.ID, now)
+	if errC != nil {
+		return nil, nil, errC
+	}
+
+	// Someone else used it between the select and the update
+	if !used {
+		return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "Authorization code was already used")
+	}
+
+	user, errC := s.userRepo.SelectUserFromId(stored.UserID)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, nil, oauthError(http.StatusBadRequest, models.OAuthInvalidGrant, "Invalid authorization code")
+		}
+		return nil, nil, errC
+	}
+
+	claims, errC := newUserClaims(s.userRepo, user)
+	if errC != nil {
+		return nil, nil, errC
+	}
+
+	claims.Scopes = stored.Scopes
+	claims.ClientID = client.ID
+
+	if !claims.HasScope(models.ScopeOpenID) {
+		return claims, nil, nil
+	}
+
+	return claims, &dtos.IDToken{
+		OIDCClaims: *newOIDCClaims(user, stored.Scopes),
+		Audience:   client.ID,
+		Nonce:      stored.Nonce,
+	}, nil
+}
+
+// clientCredentials returns the claims of the client acting for itself.
+func (s DefaultOAuthService) clientCredentials(client *dtos.OAuthClient, request *dtos.OAuthTokenRequest) (*dtos.TokenClaims, *utils.ErrorCode) {
+	scopes, errC := grantedScopes(client, request.Scope)
+	if errC != nil {
+		return nil, errC
+	}
+
+	return &dtos.TokenClaims{
+		Scopes:   scopes,
+		ClientID: client.ID,
+	}, nil
+}
+
+func (s DefaultOAuthService) authorizationCodeLifetime() time.Duration {
+	if s.settings.Auth.OAuth.AuthorizationCodeLifetime <= 0 {
+		return defaultAuthorizationCodeLifetime
+	}
+
+	return s.settings.Auth.OAuth.AuthorizationCodeLifetime
+}
+
+// grantedScopes returns the requested space separated scopes, which must be
+// allowed for the client, or every scope of the client when none is asked.
+func grantedScopes(client *dtos.OAuthClient, scope string) ([]string, *utils.ErrorCode) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return client.Scopes, nil
+	}
+
+	scopes := make([]string, 0, len(requested))
+	for _, s := range requested {
+		if !containsString(client.Scopes, s) {
+			return nil, oauthError(http.StatusBadRequest, models.OAuthInvalidScope, fmt.Sprintf("Scope '%s' is not allowed for the client", s))
+		}
+		if !containsString(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return scopes, nil
+}
+
+// newOIDCClaims returns the claims of the user the scopes grant, or all of
+// them for nil scopes.
+func newOIDCClaims(user *dtos.IdentifiedUser, scopes []string) *dtos.OIDCClaims {
+	claims := &dtos.OIDCClaims{
+		Subject: strconv.Itoa(user.ID),
+	}
+
+	if scopes == nil || containsString(scopes, models.ScopeEmail) {
+		emailVerified := user.EmailVerified
+
+		claims.Email = user.Email
+		claims.EmailVerified = &emailVerified
+	}
+
+	if scopes == nil || containsString(scopes, models.ScopeProfile) {
+		claims.PreferredUsername = user.UserName
+	}
+
+	return claims
+}
+
+// verifyCodeChallenge checks the PKCE verifier against its S256 challenge.
+func verifyCodeChallenge(verifier string, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func oauthError(statusCode int, code string, description string) *utils.ErrorCode {
+	return utils.NewErrorCodeString(statusCode, code+": "+description)
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/authorization"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func newTestOAuthService(now *time.Time) (*DefaultOAuthService, *mocks.MockedOAuthRepository, *mocks.MockedAuthenticator, *dtos.IdentifiedUser) {
+	// User IDs start at 1 like AUTO_INCREMENT, tokens of clients having none
+	userRepo := mocks.NewMockedUserRepository()
+	userRepo.IdCounter = 1
+
+	user, _ := userRepo.CreateUser(&dtos.UserWithHash{
+		UserModel: models.UserModel{UserName: "Diego", Email: "diego@mail.com"},
+		Hash:      "fb78ed1e-a121-542f-a68d-fcd21ffe83c5",
+	})
+	userRepo.AddUserRole(user.ID, models.RoleUser)
+
+	repo := mocks.NewMockedOAuthRepository()
+	authenticator := mocks.NewMockedAuthenticator()
+
+	service := NewDefaultOAuthService(repo, userRepo, authenticator, testSettings).(*DefaultOAuthService)
+	service.now = func() time.Time { return *now }
+
+	return service, repo, authenticator, user
+}
+
+func testCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func oauthErrorCode(err *utils.ErrorCode) string {
+	if err == nil {
+		return ""
+	}
+
+	return dtos.NewOAuthError(err).Error
+}
+
+func TestRegisterClient(t *testing.T) {
+	testCases := []struct {
+		request  dtos.OAuthClientRequest
+		respCode int
+	}{
+		{dtos.OAuthClientRequest{Name: "spa", Public: true, RedirectURIs: []string{"https://app.local/callback"}, GrantTypes: []string{models.GrantAuthorizationCode}}, http.StatusOK},
+		{dtos.OAuthClientRequest{Name: "svc", GrantTypes: []string{models.GrantClientCredentials}, Scopes: []string{authorization.PermUsersRead}}, http.StatusOK},
+		{dtos.OAuthClientRequest{Name: "spa", Public: true, GrantTypes: []string{models.GrantAuthorizationCode}}, http.StatusBadRequest},
+		{dtos.OAuthClientRequest{Name: "spa", Public: true, RedirectURIs: []string{"/callback"}, GrantTypes: []string{models.GrantAuthorizationCode}}, http.StatusBadRequest},
+		{dtos.OAuthClientRequest{Name: "spa", Public: true, RedirectURIs: []string{"https://app.local/#callback"}, GrantTypes: []string{models.GrantAuthorizationCode}}, http.StatusBadRequest},
+		{dtos.OAuthClientRequest{Name: "svc", Public: true, GrantTypes: []string{models.GrantClientCredentials}}, http.StatusBadRequest},
+		{dtos.OAuthClientRequest{Name: "svc", GrantTypes: []string{"password"}}, http.StatusBadRequest},
+		{dtos.OAuthClientRequest{Name: "svc", GrantTypes: []string{models.GrantClientCredentials}, Scopes: []string{"everything"}}, http.StatusBadRequest},
+	}
+
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, repo, _, _ := newTestOAuthService(&now)
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			created, err := service.RegisterClient(&_case.request)
+
+			if _case.respCode != http.StatusOK {
+				if err == nil || err.Code() != _case.respCode {
+					t.Errorf("Code should be '%d', got '%v'", _case.respCode, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _case.request.Public != (created.ClientSecret == "") {
+				t.Errorf("Only confidential clients should get a secret, got '%s'", created.ClientSecret)
+			}
+
+			stored, _ := repo.SelectOAuthClient(created.ID)
+			if !_case.request.Public && stored.SecretHash != utils.HashToken(created.ClientSecret) {
+				t.Error("Only the hash of the secret should be stored")
+			}
+		})
+	}
+}
+
+func TestOAuthAuthorizationCode(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _, _, user := newTestOAuthService(&now)
+
+	client, _ := service.RegisterClient(&dtos.OAuthClientRequest{
+		Name:         "spa",
+		Public:       true,
+		RedirectURIs: []string{"https://app.local/callback"},
+		GrantTypes:   []string{models.GrantAuthorizationCode},
+		Scopes:       []string{authorization.PermUsersRead, authorization.PermUsersUpdate},
+	})
+
+	claims := &dtos.TokenClaims{UserID: user.ID, Email: user.Email}
+	request := dtos.OAuthAuthorizeRequest{
+		ResponseType:        models.ResponseTypeCode,
+		ClientID:            client.ID,
+		Scope:               authorization.PermUsersRead,
+		State:               "xyz",
+		CodeChallenge:       testCodeChallenge(testCodeVerifier),
+		CodeChallengeMethod: models.CodeChallengeS256,
+	}
+
+	unregistered := request
+	unregistered.RedirectURI = "https://evil.local/callback"
+	if _, err := service.Authorize(claims, &unregistered); oauthErrorCode(err) != models.OAuthInvalidRequest {
+		t.Errorf("Unregistered redirect URI should be rejected, got '%v'", err)
+	}
+
+	plain := request
+	plain.CodeChallengeMethod = "plain"
+	if _, err := service.Authorize(claims, &plain); oauthErrorCode(err) != models.OAuthInvalidRequest {
+		t.Errorf("Plain code challenges should be rejected, got '%v'", err)
+	}
+
+	widerScope := request
+	widerScope.Scope = authorization.PermUsersDelete
+	if _, err := service.Authorize(claims, &widerScope); oauthErrorCode(err) != models.OAuthInvalidScope {
+		t.Errorf("Scopes not allowed for the client should be rejected, got '%v'", err)
+	}
+
+	delegated := &dtos.TokenClaims{UserID: user.ID, Email: user.Email, APIKeyID: 1}
+	if _, err := service.Authorize(delegated, &request); err == nil || err.Code() != http.StatusForbidden {
+		t.Errorf("API keys should not authorize clients, got '%v'", err)
+	}
+
+	granted, err := service.Authorize(claims, &request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redirectTo, _ := url.Parse(granted.RedirectTo)
+	if !strings.HasPrefix(granted.RedirectTo, "https://app.local/callback?") || redirectTo.Query().Get("state") != "xyz" {
+		t.Fatalf("Should redirect to the registered URI with the state, got '%s'", granted.RedirectTo)
+	}
+
+	code := redirectTo.Query().Get("code")
+
+	wrongVerifier := &dtos.OAuthTokenRequest{GrantType: models.GrantAuthorizationCode, ClientID: client.ID, Code: code, CodeVerifier: strings.Repeat("a", 43)}
+	if _, err := service.Token(wrongVerifier); oauthErrorCode(err) != models.OAuthInvalidGrant {
+		t.Errorf("Wrong code verifier should be rejected, got '%v'", err)
+	}
+
+	exchange := &dtos.OAuthTokenRequest{GrantType: models.GrantAuthorizationCode, ClientID: client.ID, Code: code, CodeVerifier: testCodeVerifier}
+
+	token, err := service.Token(exchange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.TokenType != "Bearer" || token.Scope != authorization.PermUsersRead || token.ExpiresIn <= 0 {
+		t.Errorf("Should get a users:read bearer token, got %+v", token)
+	}
+	if token.AccessToken != fmt.Sprintf("valid-for(%d)[%s]{%s}|%s", user.ID, user.Email, authorization.PermUsersRead, client.ID) {
+		t.Errorf("Access token should be of the user for the client, got '%s'", token.AccessToken)
+	}
+
+	if _, err := service.Token(exchange); oauthErrorCode(err) != models.OAuthInvalidGrant {
+		t.Errorf("Used authorization code should be rejected, got '%v'", err)
+	}
+
+	// An expired code
+	granted, _ = service.Authorize(claims, &request)
+	redirectTo, _ = url.Parse(granted.RedirectTo)
+	exchange.Code = redirectTo.Query().Get("code")
+
+	now = now.Add(2 * time.Minute)
+
+	if _, err := service.Token(exchange); oauthErrorCode(err) != models.OAuthInvalidGrant {
+		t.Errorf("Expired authorization code should be rejected, got '%v'", err)
+	}
+}
+
+func TestOAuthClientCredentials(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _, _, _ := newTestOAuthService(&now)
+
+	client, _ := service.RegisterClient(&dtos.OAuthClientRequest{
+		Name:       "svc",
+		GrantTypes: []string{models.GrantClientCredentials},
+		Scopes:     []string{authorization.PermUsersRead},
+	})
+
+	testCases := []struct {
+		request dtos.OAuthTokenRequest
+		errCode string
+	}{
+		{dtos.OAuthTokenRequest{GrantType: models.GrantClientCredentials, ClientID: client.ID, ClientSecret: client.ClientSecret}, ""},
+		{dtos.OAuthTokenRequest{GrantType: models.GrantClientCredentials, ClientID: client.ID, ClientSecret: "wrong"}, models.OAuthInvalidClient},
+		{dtos.OAuthTokenRequest{GrantType: models.GrantClientCredentials, ClientID: "unknown", ClientSecret: client.ClientSecret}, models.OAuthInvalidClient},
+		{dtos.OAuthTokenRequest{GrantType: models.GrantClientCredentials}, models.OAuthInvalidClient},
+		{dtos.OAuthTokenRequest{GrantType: models.GrantAuthorizationCode, ClientID: client.ID, ClientSecret: client.ClientSecret}, models.OAuthUnauthorizedClient},
+		{dtos.OAuthTokenRequest{GrantType: "password", ClientID: client.ID, ClientSecret: client.ClientSecret}, models.OAuthUnsupportedGrantType},
+		{dtos.OAuthTokenRequest{GrantType: models.GrantClientCredentials, ClientID: client.ID, ClientSecret: client.ClientSecret, Scope: authorization.PermUsersDelete}, models.OAuthInvalidScope},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			token, err := service.Token(&_case.request)
+
+			if oauthErrorCode(err) != _case.errCode {
+				t.Fatalf("Error should be '%s', got '%v'", _case.errCode, err)
+			}
+
+			if err == nil && token.AccessToken != fmt.Sprintf("valid-for(0)[]{%s}|%s", authorization.PermUsersRead, client.ID) {
+				t.Errorf("Access token should be of the client, got '%s'", token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestOAuthIntrospectAndRevoke(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _, _, _ := newTestOAuthService(&now)
+
+	resourceServer, _ := service.RegisterClient(&dtos.OAuthClientRequest{
+		Name:       "resource server",
+		GrantTypes: []string{models.GrantClientCredentials},
+	})
+	client, _ := service.RegisterClient(&dtos.OAuthClientRequest{
+		Name:       "svc",
+		GrantTypes: []string{models.GrantClientCredentials},
+		Scopes:     []string{authorization.PermUsersRead},
+	})
+	public, _ := service.RegisterClient(&dtos.OAuthClientRequest{
+		Name:         "spa",
+		Public:       true,
+		RedirectURIs: []string{"https://app.local/callback"},
+		GrantTypes:   []string{models.GrantAuthorizationCode},
+	})
+
+	token, err := service.Token(&dtos.OAuthTokenRequest{GrantType: models.GrantClientCredentials, ClientID: client.ID, ClientSecret: client.ClientSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := &dtos.OAuthTokenLookup{Token: token.AccessToken, ClientID: resourceServer.ID, ClientSecret: resourceServer.ClientSecret}
+
+	introspection, err := service.Introspect(lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !introspection.Active || introspection.ClientID != client.ID || introspection.Subject != client.ID || introspection.Scope != authorization.PermUsersRead {
+		t.Errorf("Token should be active for the client, got %+v", introspection)
+	}
+
+	if _, err := service.Introspect(&dtos.OAuthTokenLookup{Token: token.AccessToken, ClientID: public.ID}); oauthErrorCode(err) != models.OAuthInvalidClient {
+		t.Errorf("Public clients should not introspect tokens, got '%v'", err)
+	}
+
+	if err := service.Revoke(lookup); oauthErrorCode(err) != models.OAuthUnauthorizedClient {
+		t.Errorf("Tokens of other clients should not be revoked, got '%v'", err)
+	}
+
+	if err := service.Revoke(&dtos.OAuthTokenLookup{Token: "malformed", ClientID: client.ID, ClientSecret: client.ClientSecret}); err != nil {
+		t.Errorf("Invalid tokens should be ignored, got '%v'", err)
+	}
+
+	if err := service.Revoke(&dtos.OAuthTokenLookup{Token: token.AccessToken, ClientID: client.ID, ClientSecret: client.ClientSecret}); err != nil {
+		t.Fatal(err)
+	}
+
+	introspection, err = service.Introspect(lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if introspection.Active {
+		t.Errorf("Revoked token should be inactive, got %+v", introspection)
+	}
+}
+
+func TestOAuthOpenIDConnect(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _, authenticator, user := newTestOAuthService(&now)
+
+	client, err := service.RegisterClient(&dtos.OAuthClientRequest{
+		Name:         "spa",
+		Public:       true,
+		RedirectURIs: []string{"https://app.local/callback"},
+		GrantTypes:   []string{models.GrantAuthorizationCode},
+		Scopes:       []string{models.ScopeOpenID, models.ScopeEmail, models.ScopeProfile, authorization.PermUsersRead},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	granted, err := service.Authorize(&dtos.TokenClaims{UserID: user.ID, Email: user.Email}, &dtos.OAuthAuthorizeRequest{
+		ResponseType:        models.ResponseTypeCode,
+		ClientID:            client.ID,
+		Scope:               "openid email",
+		CodeChallenge:       testCodeChallenge(testCodeVerifier),
+		CodeChallengeMethod: models.CodeChallengeS256,
+		Nonce:               "n-0S6_WzA2Mj",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redirectTo, _ := url.Parse(granted.RedirectTo)
+
+	token, err := service.Token(&dtos.OAuthTokenRequest{
+		GrantType:    models.GrantAuthorizationCode,
+		ClientID:     client.ID,
+		Code:         redirectTo.Query().Get("code"),
+		CodeVerifier: testCodeVerifier,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idToken, ok := authenticator.IDTokens[token.IDToken]
+	if !ok {
+		t.Fatalf("Should get an ID token with the openid scope, got '%s'", token.IDToken)
+	}
+	if idToken.Audience != client.ID || idToken.Nonce != "n-0S6_WzA2Mj" {
+		t.Errorf("ID token should be for the client with the nonce, got %+v", idToken)
+	}
+	if idToken.Subject != fmt.Sprint(user.ID) || idToken.Email != user.Email || idToken.EmailVerified == nil {
+		t.Errorf("ID token should carry the email claims, got %+v", idToken)
+	}
+	if idToken.PreferredUsername != "" {
+		t.Errorf("ID token should not carry profile claims without the profile scope, got '%s'", idToken.PreferredUsername)
+	}
+
+	testCases := []struct {
+		claims   *dtos.TokenClaims
+		respCode int
+		username string
+		email    string
+	}{
+		{&dtos.TokenClaims{UserID: user.ID}, http.StatusOK, "Diego", "diego@mail.com"},
+		{&dtos.TokenClaims{UserID: user.ID, Scopes: []string{models.ScopeOpenID, models.ScopeProfile}, ClientID: client.ID}, http.StatusOK, "Diego", ""},
+		{&dtos.TokenClaims{UserID: user.ID, Scopes: []string{authorization.PermUsersRead}, ClientID: client.ID}, http.StatusForbidden, "", ""},
+		{&dtos.TokenClaims{Scopes: []string{models.ScopeOpenID}, ClientID: client.ID}, http.StatusForbidden, "", ""},
+		{&dtos.TokenClaims{UserID: 42}, http.StatusUnauthorized, "", ""},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			userInfo, err := service.UserInfo(_case.claims)
+
+			if _case.respCode != http.StatusOK {
+				if err == nil || err.Code() != _case.respCode {
+					t.Errorf("Code should be '%d', got '%v'", _case.respCode, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if userInfo.PreferredUsername != _case.username || userInfo.Email != _case.email {
+				t.Errorf("Claims should be '%s' and '%s', got %+v", _case.username, _case.email, userInfo)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+// DefaultTokenService implements TokenService
+var _ interfaces.TokenService = (*DefaultTokenService)(nil)
+
+const (
+	defaultRefreshTokenLifetime = 30 * 24 * time.Hour
+	maxSessionUserAgentLength   = 255
+)
+
+// truncateUserAgent cuts the user agent to the maxSessionUserAgentLength
+// characters its columns hold, never halfway through a character, replacing
+// invalid UTF-8 the database would refuse.
+func truncateUserAgent(userAgent string) string {
+	runes := []rune(strings.ToValidUTF8(userAgent, string(utf8.RuneError)))
+	if len(runes) > maxSessionUserAgentLength {
+		runes = runes[:maxSessionUserAgentLength]
+	}
+
+	return string(runes)
+}
+
+type DefaultTokenService struct {
+	refreshRepo interfaces.RefreshTokenRepository
+	sessionRepo interfaces.SessionRepository
+	userRepo    interfaces.UserRepository
+	apiKeys     interfaces.APIKeyRepository
+	auth        interfaces.Authenticator
+	settings    *config.Settings
+	now         func() time.Time
+}
+
+func NewDefaultTokenService(
+	refreshTokenRepository interfaces.RefreshTokenRepository,
+	sessionRepository interfaces.SessionRepository,
+	userRepository interfaces.UserRepository,
+	apiKeyRepository interfaces.APIKeyRepository,
+	authenticator interfaces.Authenticator,
+	settings *config.Settings,
+) interfaces.TokenService {
+	return &DefaultTokenService{
+		refreshRepo: refreshTokenRepository,
+		sessionRepo: sessionRepository,
+		userRepo:    userRepository,
+		apiKeys:     apiKeyRepository,
+		auth:        authenticator,
+		settings:    settings,
+		now:         time.Now,
+	}
+}
+
+// IssueTokens returns an access token and a refresh token starting a new
+// token family, recorded as a session of the client.
+func (s DefaultTokenService) IssueTokens(user *dtos.IdentifiedUser, client *dtos.ClientInfo) (*dtos.TokenResponse, *utils.ErrorCode) {
+	familyId, err := utils.NewRandomToken(16)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	now := s.now().UTC()
+
+	errC := s.sessionRepo.CreateSession(&dtos.Session{
+		ID:         familyId,
+		UserID:     user.ID,
+		UserAgent:  truncateUserAgent(client.UserAgent),
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTokenLifetime()),
+	})
+	if errC != nil {
+		return nil, errC
+	}
+
+	return s.issueTokens(user, familyId)
+}
+
+// RefreshTokens exchanges a refresh token for a new pair of tokens, rotating
+// the refresh token.
+//
+// Presenting an already used refresh token revokes its whole family, since
+// either the legitimate client or an attacker holds a stolen copy of it.
+func (s DefaultTokenService) RefreshTokens(refreshToken string) (*dtos.TokenResponse, *utils.ErrorCode) {
+	stored, errC := s.refreshRepo.SelectRefreshTokenFromHash(utils.HashToken(refreshToken))
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid refresh token")
+		}
+		return nil, errC
+	}
+
+	now := s.now().UTC()
+
+	if stored.RevokedAt != nil {
+		return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Refresh token was revoked")
+	}
+
+	if stored.UsedAt != nil {
+		return nil, s.revokeReusedFamily(stored, now)
+	}
+
+	if !now.Before(stored.ExpiresAt) {
+		return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Refresh token expired")
+	}
+
+	used, errC := s.refreshRepo.UseRefreshToken(stored.ID, now)
+	if errC != nil {
+		return nil, errC
+	}
+
+	// Someone else used it between the select and the update
+	if !used {
+		return nil, s.revokeReusedFamily(stored, now)
+	}
+
+	errC = s.touchSession(stored, now)
+	if errC != nil {
+		return nil, errC
+	}
+
+	user, errC := s.userRepo.SelectUserFromId(stored.UserID)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid refresh token")
+		}
+		return nil, errC
+	}
+
+	return s.issueTokens(user, stored.FamilyID)
+}
+
+// Logout revokes the access token and the session it belongs to, along with
+// the session of the refresh token when given.
+func (s DefaultTokenService) Logout(claims *dtos.TokenClaims, refreshToken string) *utils.ErrorCode {
+	err := s.auth.RevokeToken(claims)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	now := s.now().UTC()
+
+	if claims.SessionID != "" {
+		errC := s.revokeSession(claims.UserID, claims.SessionID, now)
+		if errC != nil {
+			return errC
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, errC := s.refreshRepo.SelectRefreshTokenFromHash(utils.HashToken(refreshToken))
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil
+		}
+		return errC
+	}
+
+	// Never let someone revoke a session that isn't theirs
+	if stored.UserID != claims.UserID {
+		return nil
+	}
+
+	return s.revokeSession(stored.UserID, stored.FamilyID, now)
+}
+
+// LogoutAll revokes every access and refresh token issued to the user, every
+// session and every API key of theirs. It's how the user resets all of their
+// credentials, also on password changes and resets.
+func (s DefaultTokenService) LogoutAll(userId int) *utils.ErrorCode {
+	err := s.auth.RevokeUserTokens(userId)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	now := s.now().UTC()
+
+	errC := s.apiKeys.RevokeUserAPIKeys(userId, now)
+	if errC != nil {
+		return errC
+	}
+
+	errC = s.sessionRepo.RevokeUserSessions(userId, now)
+	if errC != nil {
+		return errC
+	}
+
+	return s.refreshRepo.RevokeUserRefreshTokens(userId, now)
+}
+
+// SelectUserSessions returns the sessions of the user still alive.
+func (s DefaultTokenService) SelectUserSessions(userId int) ([]*dtos.Session, *utils.ErrorCode) {
+	return s.sessionRepo.SelectUserSessions(userId, s.now().UTC())
+}
+
+// RevokeSession revokes a session of the user, rejecting its access tokens
+// right away and its refresh tokens.
+func (s DefaultTokenService) RevokeSession(userId int, sessionId string) *utils.ErrorCode {
+	now := s.now().UTC()
+
+	errC := s.sessionRepo.RevokeSession(userId, sessionId, now)
+	if errC != nil {
+		return errC
+	}
+
+	return s.refreshRepo.RevokeRefreshTokenFamily(sessionId, now)
+}
+
+func (s DefaultTokenService) issueTokens(user *dtos.IdentifiedUser, familyId string) (*dtos.TokenResponse, *utils.ErrorCode) {
+	claims, errC := newUserClaims(s.userRepo, user)
+	if errC != nil {
+		return nil, errC
+	}
+
+	claims.SessionID = familyId
+
+	accessToken, err := s.auth.GenerateToken(claims)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	refreshToken, err := utils.NewRandomToken(32)
+	if err != nil {
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	errC = s.refreshRepo.CreateRefreshToken(&dtos.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyId,
+		Hash:      utils.HashToken(refreshToken),
+		ExpiresAt: s.now().UTC().Add(s.refreshTokenLifetime()),
+	})
+	if errC != nil {
+		return nil, errC
+	}
+
+	return &dtos.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s DefaultTokenService) revokeReusedFamily(stored *dtos.RefreshToken, now time.Time) *utils.ErrorCode {
+	errC := s.revokeSession(stored.UserID, stored.FamilyID, now)
+	if errC != nil {
+		return errC
+	}
+
+	return utils.NewErrorCodeString(http.StatusUnauthorized, "Refresh token reuse detected, session revoked")
+}
+
+// revokeSession revokes the session and its refresh token family, even if
+// the session was already revoked.
+func (s DefaultTokenService) revokeSession(userId int, familyId string, now time.Time) *utils.ErrorCode {
+	errC := s.sessionRepo.RevokeSession(userId, familyId, now)
+	if errC != nil && errC.Code() != http.StatusNotFound {
+		return errC
+	}
+
+	return s.refreshRepo.RevokeRefreshTokenFamily(familyId, now)
+}
+
+// touchSession records the session of the refresh token was seen, extending
+// it as long as the new refresh token. Families issued before sessions
+// existed get one.
+func (s DefaultTokenService) touchSession(stored *dtos.RefreshToken, now time.Time) *utils.ErrorCode {
+	expiresAt := now.Add(s.refreshTokenLifetime())
+
+	session, errC := s.sessionRepo.SelectSession(stored.FamilyID)
+	if errC != nil {
+		if errC.Code() != http.StatusNotFound {
+			return errC
+		}
+
+		return s.sessionRepo.CreateSession(&dtos.Session{
+			ID:         stored.FamilyID,
+			UserID:     stored.UserID,
+			CreatedAt:  now,
+			LastSeenAt: now,
+			ExpiresAt:  expiresAt,
+		})
+	}
+
+	if session.RevokedAt != nil {
+		return utils.NewErrorCodeString(http.StatusUnauthorized, "Session was revoked")
+	}
+
+	return s.sessionRepo.TouchSession(session.ID, now, expiresAt)
+}
+
+func (s DefaultTokenService) refreshTokenLifetime() time.Duration {
+	if s.settings.Auth.RefreshTokenLifetime <= 0 {
+		return defaultRefreshTokenLifetime
+	}
+
+	return s.settings.Auth.RefreshTokenLifetime
+}
+
+// newUserClaims returns the access token claims of the user, with their
+// current roles.
+func newUserClaims(userRepo interfaces.UserRepository, user *dtos.IdentifiedUser) (*dtos.TokenClaims, *utils.ErrorCode) {
+	roles, errC := userRepo.SelectUserRoles(user.ID)
+	if errC != nil {
+		return nil, errC
+	}
+
+	return &dtos.TokenClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Roles:  roles,
+	}, nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/d1360-64rc14/simple-api/authentication"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+var testSettings = &config.Settings{
+	Auth: config.Auth{
+		BCryptCost:           4,
+		RefreshTokenLifetime: time.Hour,
+	},
+}
+
+func newTestTokenService(now *time.Time) (*DefaultTokenService, *mocks.MockedRefreshTokenRepository, *dtos.IdentifiedUser) {
+	userRepo := mocks.NewMockedUserRepository()
+	user, _ := userRepo.CreateUser(&dtos.UserWithHash{
+		UserModel: models.UserModel{UserName: "Diego", Email: "diego@mail.com"},
+		Hash:      "fb78ed1e-a121-542f-a68d-fcd21ffe83c5",
+	})
+
+	refreshRepo := mocks.NewMockedRefreshTokenRepository()
+
+	service := NewDefaultTokenService(refreshRepo, mocks.NewMockedSessionRepository(), userRepo, mocks.NewMockedAPIKeyRepository(), mocks.NewMockedAuthenticator(), testSettings).(*DefaultTokenService)
+	service.now = func() time.Time { return *now }
+
+	return service, refreshRepo, user
+}
+
+func TestRefreshTokens_Rotation(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _, user := newTestTokenService(&now)
+
+	issued, err := service.IssueTokens(user, &dtos.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(issued.Token, "valid-for(0)[diego@mail.com]<") {
+		t.Errorf("access token should be 'valid-for(0)[diego@mail.com]' with a session, got '%s'", issued.Token)
+	}
+
+	now = now.Add(30 * time.Minute)
+
+	refreshed, err := service.RefreshTokens(issued.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken == issued.RefreshToken {
+		t.Error("refresh token should be rotated")
+	}
+
+	// The rotated token lives for a full lifetime from its own issuance
+	now = now.Add(50 * time.Minute)
+
+	_, err = service.RefreshTokens(refreshed.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshTokens_ReuseRevokesFamily(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, refreshRepo, user := newTestTokenService(&now)
+
+	issued, _ := service.IssueTokens(user, &dtos.ClientInfo{})
+	otherSession, _ := service.IssueTokens(user, &dtos.ClientInfo{})
+
+	refreshed, err := service.RefreshTokens(issued.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.RefreshTokens(issued.RefreshToken)
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token should be rejected with 401, got %v", err)
+	}
+
+	_, err = service.RefreshTokens(refreshed.RefreshToken)
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Fatalf("refresh token of a revoked family should be rejected with 401, got %v", err)
+	}
+
+	for _, token := range refreshRepo.Tokens {
+		if token.Hash == issued.RefreshToken || token.Hash == refreshed.RefreshToken {
+			t.Fatal("refresh tokens should never be stored in plain text")
+		}
+	}
+
+	_, err = service.RefreshTokens(otherSession.RefreshToken)
+	if err != nil {
+		t.Errorf("other token families should not be revoked, got %v", err)
+	}
+}
+
+func TestRefreshTokens_Invalid(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service, _, user := newTestTokenService(&now)
+
+	issued, _ := service.IssueTokens(user, &dtos.ClientInfo{})
+
+	_, err := service.RefreshTokens("unknown-refresh-token")
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Errorf("unknown refresh token should be rejected with 401, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+
+	_, err = service.RefreshTokens(issued.RefreshToken)
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Errorf("expired refresh token should be rejected with 401, got %v", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	userRepo := mocks.NewMockedUserRepository()
+	user, _ := userRepo.CreateUser(&dtos.UserWithHash{
+		UserModel: models.UserModel{UserName: "Diego", Email: "diego@mail.com"},
+		Hash:      "fb78ed1e-a121-542f-a68d-fcd21ffe83c5",
+	})
+
+	sessionRepo := mocks.NewMockedSessionRepository()
+	authenticator := authentication.NewSessionAuthenticator(mocks.NewMockedAuthenticator(), sessionRepo)
+
+	service := NewDefaultTokenService(mocks.NewMockedRefreshTokenRepository(), sessionRepo, userRepo, mocks.NewMockedAPIKeyRepository(), authenticator, testSettings).(*DefaultTokenService)
+	service.now = func() time.Time { return now }
+
+	laptop, _ := service.IssueTokens(user, &dtos.ClientInfo{IP: "10.0.0.1", UserAgent: "Firefox"})
+	phone, _ := service.IssueTokens(user, &dtos.ClientInfo{IP: "10.0.0.2", UserAgent: "Safari"})
+
+	sessions, err := service.SelectUserSessions(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].UserAgent != "Firefox" || sessions[1].IP != "10.0.0.2" {
+		t.Fatalf("both sessions should be listed with their client, got %+v", sessions)
+	}
+
+	laptopClaims, parseErr := authenticator.ParseToken(laptop.Token)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if laptopClaims.SessionID != sessions[0].ID {
+		t.Fatalf("access token should carry its session ID '%s', got '%s'", sessions[0].ID, laptopClaims.SessionID)
+	}
+
+	now = now.Add(10 * time.Minute)
+
+	phone, err = service.RefreshTokens(phone.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sessionRepo.Sessions[1].LastSeenAt.Equal(now) {
+		t.Errorf("refreshing should update the last seen time to %s, got %s", now, sessionRepo.Sessions[1].LastSeenAt)
+	}
+
+	err = service.RevokeSession(user.ID+1, laptopClaims.SessionID)
+	if err == nil || err.Code() != http.StatusNotFound {
+		t.Fatalf("sessions of other users should not be found, got %v", err)
+	}
+
+	if err := service.RevokeSession(user.ID, laptopClaims.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := authenticator.ParseToken(laptop.Token); !errors.Is(err, utils.ErrTokenRevoked) {
+		t.Errorf("access token of a revoked session should be rejected right away, got %v", err)
+	}
+	if _, err := service.RefreshTokens(laptop.RefreshToken); err == nil || err.Code() != http.StatusUnauthorized {
+		t.Errorf("refresh token of a revoked session should be rejected with 401, got %v", err)
+	}
+
+	if _, err := authenticator.ParseToken(phone.Token); err != nil {
+		t.Errorf("other sessions should keep working, got %v", err)
+	}
+
+	sessions, _ = service.SelectUserSessions(user.ID)
+	if len(sessions) != 1 || sessions[0].UserAgent != "Safari" {
+		t.Errorf("only the phone session should be left, got %+v", sessions)
+	}
+}
+
+func TestTruncateUserAgent(t *testing.T) {
+	testCases := []struct {
+		userAgent string
+		expected  string
+	}{
+		{"curl/8.0.1", "curl/8.0.1"},
+		{strings.Repeat("a", 300), strings.Repeat("a", 255)},
+		{strings.Repeat("é", 300), strings.Repeat("é", 255)},
+		{strings.Repeat("a", 254) + "日本", strings.Repeat("a", 254) + "日"},
+		{"bad\xffbyte", "bad\uFFFDbyte"},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			truncated := truncateUserAgent(_case.userAgent)
+			if truncated != _case.expected {
+				t.Errorf("User agent should be '%s', got '%s'", _case.expected, truncated)
+			}
+			if !utf8.ValidString(truncated) {
+				t.Errorf("User agent should be valid UTF-8, got '%q'", truncated)
+			}
+		})
+	}
+}
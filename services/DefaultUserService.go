@@ -2,48 +2,108 @@ package services
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/d1360-64rc14/simple-api/authorization"
 	"github.com/d1360-64rc14/simple-api/config"
 	"github.com/d1360-64rc14/simple-api/dtos"
 	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/models"
 	"github.com/d1360-64rc14/simple-api/utils"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // DefaultUserService implements UserService
 var _ interfaces.UserService = (*DefaultUserService)(nil)
 
+const (
+	defaultPasswordResetTokenLifetime     = time.Hour
+	defaultEmailVerificationTokenLifetime = 24 * time.Hour
+)
+
 type DefaultUserService struct {
-	repo     interfaces.UserRepository
-	auth     interfaces.Authenticator
-	settings *config.Settings
+	repo       interfaces.UserRepository
+	userTokens interfaces.UserTokenRepository
+	history    interfaces.LoginHistoryRepository
+	auth       interfaces.Authenticator
+	tokens     interfaces.TokenService
+	hasher     interfaces.PasswordHasher
+	mfa        interfaces.MFAService
+	lockouts   interfaces.LockoutService
+	mail       interfaces.MailSender
+	cursors    interfaces.CursorSigner
+	settings   *config.Settings
+	now        func() time.Time
+	// dummyHash is verified against for unknown emails, so they take as long
+	// as wrong passwords
+	dummyHash string
 }
 
 func NewDefaultUserService(
 	userRepository interfaces.UserRepository,
+	userTokenRepository interfaces.UserTokenRepository,
+	loginHistoryRepository interfaces.LoginHistoryRepository,
 	authenticator interfaces.Authenticator,
+	tokenService interfaces.TokenService,
+	passwordHasher interfaces.PasswordHasher,
+	mfaService interfaces.MFAService,
+	lockoutService interfaces.LockoutService,
+	mailSender interfaces.MailSender,
+	cursorSigner interfaces.CursorSigner,
 	settings *config.Settings,
 ) interfaces.UserService {
+	dummyHash, err := passwordHasher.Hash("not a real password")
+	if err != nil {
+		log.Printf("could not make dummy password hash: %s", err)
+	}
+
 	return &DefaultUserService{
-		repo:     userRepository,
-		auth:     authenticator,
-		settings: settings,
+		repo:       userRepository,
+		userTokens: userTokenRepository,
+		history:    loginHistoryRepository,
+		auth:       authenticator,
+		tokens:     tokenService,
+		hasher:     passwordHasher,
+		mfa:        mfaService,
+		lockouts:   lockoutService,
+		mail:       mailSender,
+		cursors:    cursorSigner,
+		settings:   settings,
+		now:        time.Now,
+		dummyHash:  dummyHash,
 	}
 }
 
 func (s DefaultUserService) CreateUser(user dtos.UserWithPassword) (*dtos.IdentifiedUser, *utils.ErrorCode) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), s.settings.Auth.BCryptCost)
+	errC := s.checkPasswordLength(user.Password)
+	if errC != nil {
+		return nil, errC
+	}
+
+	hash, err := s.hasher.Hash(user.Password)
 	if err != nil {
-		return nil, utils.NewErrorCode(http.StatusBadRequest, err)
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
 	}
 
 	userHash := &dtos.UserWithHash{
 		UserModel: user.UserModel,
-		Hash:      string(hash),
+		Hash:      hash,
+	}
+
+	createdUser, errC := s.repo.CreateUser(userHash)
+	if errC != nil {
+		return nil, errC
 	}
 
-	return s.repo.CreateUser(userHash)
+	// The account exists anyway, the user can ask for another verification mail
+	errC = s.sendVerification(createdUser)
+	if errC != nil {
+		log.Printf("could not send verification mail to user %d: %s", createdUser.ID, errC)
+	}
+
+	return createdUser, nil
 }
 
 func (s DefaultUserService) SelectUserFromId(id int) (*dtos.IdentifiedUser, *utils.ErrorCode) {
@@ -62,6 +122,108 @@ func (s DefaultUserService) SelectAllUsers() ([]*dtos.IdentifiedUser, *utils.Err
 	return s.repo.SelectAllUsers()
 }
 
+// SelectUsers returns the page of users selected by query, with the cursors
+// of the pages around it. The links to them are left to the caller, who
+// knows where it is served.
+func (s DefaultUserService) SelectUsers(query *dtos.UserQuery) (*dtos.LinkedPage[*dtos.IdentifiedUser], *utils.ErrorCode) {
+	if query.Cursor != nil && query.Sort != "" && query.Sort != query.Cursor.Sort {
+		return nil, utils.NewErrorCodeString(http.StatusBadRequest, "The cursor was made for another sort")
+	}
+
+	query.Normalize()
+
+	// Peeking one user further tells if there is a page after this one
+	limit := query.Limit
+	query.Limit++
+	users, total, errC := s.repo.SelectUsers(query)
+	query.Limit = limit
+	if errC != nil {
+		return nil, errC
+	}
+
+	return linkPage(s.cursors, users, limit, query.Offset, total, query.Cursor, func(user *dtos.IdentifiedUser, backward bool) *dtos.Cursor {
+		return userCursor(query.Sort, user, backward)
+	})
+}
+
+// SearchUsers returns the users whose username or email match query, the
+// most relevant first.
+func (s DefaultUserService) SearchUsers(query *dtos.UserSearchQuery) ([]*dtos.IdentifiedUser, *utils.ErrorCode) {
+	if len(query.Terms()) == 0 {
+		return nil, utils.NewErrorCodeString(http.StatusBadRequest, "Search should have at least a letter or a digit")
+	}
+
+	query.Normalize()
+
+	return s.repo.SearchUsers(query)
+}
+
+// linkPage makes the page of the items selected at offset or at cursor,
+// signing the cursors of the pages around it with cursorOf. Items past limit,
+// peeked by the caller, only tell there is a page after this one.
+func linkPage[T any](
+	cursors interfaces.CursorSigner,
+	items []T,
+	limit int,
+	offset int,
+	total int,
+	cursor *dtos.Cursor,
+	cursorOf func(item T, backward bool) *dtos.Cursor,
+) (*dtos.LinkedPage[T], *utils.ErrorCode) {
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &dtos.LinkedPage[T]{
+		Items:  items,
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	}
+
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	hasNext := more || backward
+	hasPrev := (more && backward) || (!backward && (cursor != nil || offset > 0))
+
+	var err error
+
+	if hasNext {
+		page.NextCursor, err = cursors.Sign(cursorOf(items[len(items)-1], false))
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+	}
+	if hasPrev {
+		page.PrevCursor, err = cursors.Sign(cursorOf(items[0], true))
+		if err != nil {
+			return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		}
+	}
+
+	return page, nil
+}
+
+// userCursor is the position of user in the users ordered by sort.
+func userCursor(sort string, user *dtos.IdentifiedUser, backward bool) *dtos.Cursor {
+	cursor := &dtos.Cursor{Sort: sort, ID: user.ID, Backward: backward}
+	if strings.TrimPrefix(sort, "-") == "username" {
+		cursor.Key = user.UserName
+	}
+
+	return cursor
+}
+
 func (s DefaultUserService) RemoveUser(id int) *utils.ErrorCode {
 	userExist, err := s.repo.UserExist(id)
 	if err != nil {
@@ -101,6 +263,343 @@ func (s DefaultUserService) UpdateUser(id int, newUserData *dtos.UserUpdate) *ut
 	return nil
 }
 
+// ChangePassword replaces the user password when the current one matches,
+// then revokes every session of the user.
+func (s DefaultUserService) ChangePassword(id int, passwordChange *dtos.PasswordChange) *utils.ErrorCode {
+	errC := s.checkPasswordLength(passwordChange.NewPassword)
+	if errC != nil {
+		return errC
+	}
+
+	userHash, errC := s.repo.SelectUserHashFromId(id)
+	if errC != nil {
+		return errC
+	}
+
+	ok, err := s.hasher.Verify(passwordChange.CurrentPassword, userHash)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	if !ok {
+		return utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid current password")
+	}
+
+	newHash, err := s.hasher.Hash(passwordChange.NewPassword)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	errC = s.repo.UpdateUserHash(id, newHash)
+	if errC != nil {
+		return errC
+	}
+
+	return s.tokens.LogoutAll(id)
+}
+
+// ForgotPassword mails a single-use password reset token to the user.
+//
+// Unknown emails are silently ignored so the caller can't tell which ones
+// have an account.
+func (s DefaultUserService) ForgotPassword(passwordForgot *dtos.PasswordForgot) *utils.ErrorCode {
+	user, errC := s.repo.SelectUserFromEmail(passwordForgot.Email)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil
+		}
+		return errC
+	}
+
+	lifetime := s.settings.Auth.PasswordResetTokenLifetime
+	if lifetime <= 0 {
+		lifetime = defaultPasswordResetTokenLifetime
+	}
+
+	token, expiresAt, errC := s.issueUserToken(user.ID, models.PurposePasswordReset, "", lifetime)
+	if errC != nil {
+		return errC
+	}
+
+	err := s.mail.SendMail(&dtos.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your account.\n\n"+
+				"Use the following token to choose a new one before %s:\n\n%s\n\n"+
+				"If it wasn't you, ignore this mail.",
+			expiresAt.Format(time.RFC1123),
+			token,
+		),
+	})
+	if err != nil {
+		log.Printf("could not mail password reset token to user %d: %s", user.ID, err)
+	}
+
+	return nil
+}
+
+// ResetPassword consumes a password reset token, replacing the user password
+// and revoking every session of the user.
+//
+// The new password is checked before the token is consumed, so a refused one
+// doesn't cost the user their token.
+func (s DefaultUserService) ResetPassword(passwordReset *dtos.PasswordReset) *utils.ErrorCode {
+	errC := s.checkPasswordLength(passwordReset.NewPassword)
+	if errC != nil {
+		return errC
+	}
+
+	userToken, errC := s.consumeUserToken(models.PurposePasswordReset, passwordReset.Token)
+	if errC != nil {
+		return errC
+	}
+
+	newHash, err := s.hasher.Hash(passwordReset.NewPassword)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	errC = s.repo.UpdateUserHash(userToken.UserID, newHash)
+	if errC != nil {
+		return errC
+	}
+
+	return s.tokens.LogoutAll(userToken.UserID)
+}
+
+// checkPasswordLength fails with 400 if the password is longer than the
+// configured hashing algorithm accepts.
+func (s DefaultUserService) checkPasswordLength(password string) *utils.ErrorCode {
+	maxLength := s.hasher.MaxPasswordLength()
+	if maxLength > 0 && len(password) > maxLength {
+		return utils.NewErrorCodeString(
+			http.StatusBadRequest,
+			fmt.Sprintf("Password can't be longer than %d bytes", maxLength),
+		)
+	}
+
+	return nil
+}
+
+// VerifyEmail consumes an email verification token, marking the user email
+// address as verified.
+func (s DefaultUserService) VerifyEmail(token string) *utils.ErrorCode {
+	userToken, errC := s.consumeUserToken(models.PurposeEmailVerification, token)
+	if errC != nil {
+		return errC
+	}
+
+	return s.repo.VerifyUserEmail(userToken.UserID, s.now().UTC())
+}
+
+// ResendVerification mails a new email verification token to the user.
+//
+// Unknown and already verified emails are silently ignored so the caller
+// can't tell which ones have an account.
+func (s DefaultUserService) ResendVerification(verificationResend *dtos.VerificationResend) *utils.ErrorCode {
+	user, errC := s.repo.SelectUserFromEmail(verificationResend.Email)
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil
+		}
+		return errC
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	return s.sendVerification(user)
+}
+
+// sendVerification mails a new email verification token to the user. Mailing
+// failures are only logged.
+func (s DefaultUserService) sendVerification(user *dtos.IdentifiedUser) *utils.ErrorCode {
+	lifetime := s.settings.Auth.EmailVerificationTokenLifetime
+	if lifetime <= 0 {
+		lifetime = defaultEmailVerificationTokenLifetime
+	}
+
+	token, expiresAt, errC := s.issueUserToken(user.ID, models.PurposeEmailVerification, "", lifetime)
+	if errC != nil {
+		return errC
+	}
+
+	err := s.mail.SendMail(&dtos.Mail{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Welcome, %s!\n\n"+
+				"Use the following token to verify your email address before %s:\n\n%s\n\n"+
+				"If you didn't create an account, ignore this mail.",
+			user.UserName,
+			expiresAt.Format(time.RFC1123),
+			token,
+		),
+	})
+	if err != nil {
+		log.Printf("could not mail verification token to user %d: %s", user.ID, err)
+	}
+
+	return nil
+}
+
+// RequestEmailChange mails a token to the new email address of the user. The
+// current address stays in use until the token is confirmed.
+func (s DefaultUserService) RequestEmailChange(id int, emailChange *dtos.EmailChange) *utils.ErrorCode {
+	user, errC := s.repo.SelectUserFromId(id)
+	if errC != nil {
+		return errC
+	}
+
+	if user.Email == emailChange.Email {
+		return utils.NewErrorCodeString(http.StatusBadRequest, "New email address is the current one")
+	}
+
+	_, errC = s.repo.SelectUserFromEmail(emailChange.Email)
+	if errC == nil {
+		return utils.NewErrorCodeString(http.StatusConflict, "Email address already exist")
+	}
+	if errC.Code() != http.StatusNotFound {
+		return errC
+	}
+
+	lifetime := s.settings.Auth.EmailVerificationTokenLifetime
+	if lifetime <= 0 {
+		lifetime = defaultEmailVerificationTokenLifetime
+	}
+
+	token, expiresAt, errC := s.issueUserToken(user.ID, models.PurposeEmailChange, emailChange.Email, lifetime)
+	if errC != nil {
+		return errC
+	}
+
+	err := s.mail.SendMail(&dtos.Mail{
+		To:      emailChange.Email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Hi, %s!\n\n"+
+				"Use the following token to confirm this is your new email address before %s:\n\n%s\n\n"+
+				"If you didn't ask for it, ignore this mail.",
+			user.UserName,
+			expiresAt.Format(time.RFC1123),
+			token,
+		),
+	})
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange consumes an email change token, replacing the user email
+// address and notifying the previous one.
+//
+// Access tokens of the user are revoked, so the new address is in their next
+// refreshed token.
+func (s DefaultUserService) ConfirmEmailChange(token string) *utils.ErrorCode {
+	userToken, errC := s.consumeUserToken(models.PurposeEmailChange, token)
+	if errC != nil {
+		return errC
+	}
+
+	user, errC := s.repo.SelectUserFromId(userToken.UserID)
+	if errC != nil {
+		return errC
+	}
+
+	previousEmail := user.Email
+
+	errC = s.repo.UpdateUserEmail(user.ID, userToken.Payload, s.now().UTC())
+	if errC != nil {
+		return errC
+	}
+
+	err := s.auth.RevokeUserTokens(user.ID)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	err = s.mail.SendMail(&dtos.Mail{
+		To:      previousEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(
+			"Hi, %s!\n\n"+
+				"The email address of your account was changed to %s.\n\n"+
+				"If it wasn't you, contact us right away.",
+			user.UserName,
+			userToken.Payload,
+		),
+	})
+	if err != nil {
+		log.Printf("could not notify user %d of their email change: %s", user.ID, err)
+	}
+
+	return nil
+}
+
+// issueUserToken stores a new single-use token, invalidating the previous
+// ones of the same purpose.
+func (s DefaultUserService) issueUserToken(userId int, purpose string, payload string, lifetime time.Duration) (string, time.Time, *utils.ErrorCode) {
+	token, err := utils.NewRandomToken(32)
+	if err != nil {
+		return "", time.Time{}, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	now := s.now().UTC()
+
+	errC := s.userTokens.InvalidateUserTokens(userId, purpose, now)
+	if errC != nil {
+		return "", time.Time{}, errC
+	}
+
+	expiresAt := now.Add(lifetime)
+
+	errC = s.userTokens.CreateUserToken(&dtos.UserToken{
+		UserID:    userId,
+		Purpose:   purpose,
+		Hash:      utils.HashToken(token),
+		Payload:   payload,
+		ExpiresAt: expiresAt,
+	})
+	if errC != nil {
+		return "", time.Time{}, errC
+	}
+
+	return token, expiresAt, nil
+}
+
+// consumeUserToken marks the token as used, failing with 400 if it's unknown,
+// expired or already used.
+func (s DefaultUserService) consumeUserToken(purpose string, token string) (*dtos.UserToken, *utils.ErrorCode) {
+	invalid := utils.NewErrorCodeString(http.StatusBadRequest, "Invalid or expired token")
+
+	userToken, errC := s.userTokens.SelectUserTokenFromHash(purpose, utils.HashToken(token))
+	if errC != nil {
+		if errC.Code() == http.StatusNotFound {
+			return nil, invalid
+		}
+		return nil, errC
+	}
+
+	now := s.now().UTC()
+
+	if userToken.UsedAt != nil || !now.Before(userToken.ExpiresAt) {
+		return nil, invalid
+	}
+
+	used, errC := s.userTokens.UseUserToken(userToken.ID, now)
+	if errC != nil {
+		return nil, errC
+	}
+	if !used {
+		return nil, invalid
+	}
+
+	return userToken, nil
+}
+
 // AuthenticateUser returns the JWT token as result of the authentication
 func (s DefaultUserService) AuthenticateUser(email string) (string, *utils.ErrorCode) {
 	user, errC := s.repo.SelectUserFromEmail(email)
@@ -108,7 +607,12 @@ func (s DefaultUserService) AuthenticateUser(email string) (string, *utils.Error
 		return "", errC
 	}
 
-	jwtToken, err := s.auth.GenerateToken(user.ID, user.Email)
+	claims, errC := newUserClaims(s.repo, user)
+	if errC != nil {
+		return "", errC
+	}
+
+	jwtToken, err := s.auth.GenerateToken(claims)
 	if err != nil {
 		return "", utils.NewErrorCode(http.StatusInternalServerError, err)
 	}
@@ -116,28 +620,202 @@ func (s DefaultUserService) AuthenticateUser(email string) (string, *utils.Error
 	return jwtToken, nil
 }
 
+// LoginUser returns the user tokens when the password matches, upgrading
+// their password hash if it was made with an outdated algorithm or cost.
+//
+// Users with two-factor authentication get an MFA token instead, to be
+// exchanged for their tokens along with a code.
+//
+// Unknown emails and wrong passwords fail alike with 401, counting towards
+// the lockout of the account and client IP.
 func (s DefaultUserService) LoginUser(request *dtos.LoginRequest) (*dtos.TokenResponse, *utils.ErrorCode) {
-	user, errC := s.repo.SelectUserFromEmail(request.Email)
+	errC := s.lockouts.CheckLogin(request.Email, request.ClientIP)
 	if errC != nil {
+		if errC.Code() == http.StatusTooManyRequests {
+			user, _ := s.repo.SelectUserFromEmail(request.Email)
+			s.recordLogin(request, user, false, models.LoginReasonLockedOut)
+		}
 		return nil, errC
 	}
 
+	user, errC := s.repo.SelectUserFromEmail(request.Email)
+	if errC != nil {
+		if errC.Code() != http.StatusNotFound {
+			return nil, errC
+		}
+
+		s.hasher.Verify(request.Password, s.dummyHash)
+
+		s.recordLogin(request, nil, false, models.LoginReasonUnknownEmail)
+		return nil, s.failLogin(request)
+	}
+
 	userHash, errC := s.repo.SelectUserHashFromId(user.ID)
 	if errC != nil {
 		return nil, errC
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(userHash), []byte(request.Password))
+	ok, err := s.hasher.Verify(request.Password, userHash)
 	if err != nil {
-		return nil, utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid password")
+		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+	if !ok {
+		s.recordLogin(request, user, false, models.LoginReasonInvalidPassword)
+		return nil, s.failLogin(request)
+	}
+
+	if s.settings.Auth.RequireVerifiedEmail && !user.EmailVerified {
+		s.recordLogin(request, user, false, models.LoginReasonEmailNotVerified)
+		return nil, utils.NewErrorCodeString(http.StatusForbidden, "Email address is not verified")
+	}
+
+	if s.hasher.NeedsRehash(userHash) {
+		s.rehash(user.ID, request.Password)
+	}
+
+	mfaEnabled, errC := s.mfa.IsTOTPEnabled(user.ID)
+	if errC != nil {
+		return nil, errC
+	}
+
+	// The login is recorded, and the failures of the account forgotten, once
+	// the second factor is checked
+	if mfaEnabled {
+		return s.mfa.IssueMFAToken(user)
+	}
+
+	tokens, errC := s.tokens.IssueTokens(user, &dtos.ClientInfo{IP: request.ClientIP, UserAgent: request.UserAgent})
+	if errC != nil {
+		return nil, errC
+	}
+
+	errC = s.lockouts.RecordSuccessfulLogin(request.Email)
+	if errC != nil {
+		return nil, errC
+	}
+
+	s.recordLogin(request, user, true, models.LoginReasonPassword)
+
+	return tokens, nil
+}
+
+// SelectUserLogins returns the page of the login history of the user
+// selected by query, newest first, with the cursors of the pages around it.
+func (s DefaultUserService) SelectUserLogins(id int, query *dtos.LoginHistoryQuery) (*dtos.LinkedPage[*dtos.LoginAttempt], *utils.ErrorCode) {
+	if query.Cursor != nil && query.Cursor.Sort != dtos.LoginHistorySort {
+		return nil, utils.NewErrorCodeString(http.StatusBadRequest, "The cursor was made for another list")
+	}
+
+	query.Normalize()
+
+	// Peeking one attempt further tells if there is a page after this one
+	limit := query.Limit
+	query.Limit++
+	attempts, total, errC := s.history.SelectUserLoginAttempts(id, query)
+	query.Limit = limit
+	if errC != nil {
+		return nil, errC
 	}
 
-	jwtToken, err := s.auth.GenerateToken(user.ID, user.Email)
+	return linkPage(s.cursors, attempts, limit, query.Offset, total, query.Cursor, loginAttemptCursor)
+}
+
+// loginAttemptCursor is the position of attempt in the login history.
+func loginAttemptCursor(attempt *dtos.LoginAttempt, backward bool) *dtos.Cursor {
+	return &dtos.Cursor{
+		Sort:     dtos.LoginHistorySort,
+		Key:      attempt.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ID:       attempt.ID,
+		Backward: backward,
+	}
+}
+
+// failLogin records the failed login, returning the error to respond with.
+func (s DefaultUserService) failLogin(request *dtos.LoginRequest) *utils.ErrorCode {
+	errC := s.lockouts.RecordFailedLogin(request.Email, request.ClientIP)
+	if errC != nil {
+		return errC
+	}
+
+	return utils.NewErrorCodeString(http.StatusUnauthorized, "Invalid email or password")
+}
+
+// recordLogin appends the login to the history of user, nil when the email
+// has no account.
+func (s DefaultUserService) recordLogin(request *dtos.LoginRequest, user *dtos.IdentifiedUser, succeeded bool, reason string) {
+	attempt := &dtos.LoginAttempt{
+		Email:     request.Email,
+		IP:        request.ClientIP,
+		UserAgent: request.UserAgent,
+		Succeeded: succeeded,
+		Reason:    reason,
+		CreatedAt: s.now().UTC(),
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+
+	recordLoginAttempt(s.history, s.repo, attempt)
+}
+
+// rehash replaces the user password hash. Failing isn't fatal, the old hash
+// still works and will be upgraded on the next login.
+func (s DefaultUserService) rehash(id int, password string) {
+	hash, err := s.hasher.Hash(password)
 	if err != nil {
-		return nil, utils.NewErrorCode(http.StatusInternalServerError, err)
+		log.Printf("could not rehash password of user %d: %s", id, err)
+		return
+	}
+
+	errC := s.repo.UpdateUserHash(id, hash)
+	if errC != nil {
+		log.Printf("could not store rehashed password of user %d: %s", id, errC)
+	}
+}
+
+func (s DefaultUserService) SelectUserRoles(id int) ([]string, *utils.ErrorCode) {
+	return s.repo.SelectUserRoles(id)
+}
+
+func (s DefaultUserService) GrantRole(id int, role string) *utils.ErrorCode {
+	if !authorization.IsKnownRole(role) {
+		return utils.NewErrorCodeString(http.StatusBadRequest, fmt.Sprintf("Unknown role '%s'", role))
 	}
 
-	return &dtos.TokenResponse{
-		Token: jwtToken,
-	}, nil
+	return s.repo.AddUserRole(id, role)
+}
+
+// RevokeRole removes a role from the user, revoking their access tokens so the
+// role is gone from their next refreshed token.
+func (s DefaultUserService) RevokeRole(id int, role string) *utils.ErrorCode {
+	errC := s.repo.RemoveUserRole(id, role)
+	if errC != nil {
+		return errC
+	}
+
+	err := s.auth.RevokeUserTokens(id)
+	if err != nil {
+		return utils.NewErrorCode(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// recordLoginAttempt appends the attempt to the login history, updating the
+// last login time of the user on success. Failing isn't fatal, the login goes
+// on without being recorded.
+func recordLoginAttempt(history interfaces.LoginHistoryRepository, userRepo interfaces.UserRepository, attempt *dtos.LoginAttempt) {
+	attempt.UserAgent = truncateUserAgent(attempt.UserAgent)
+
+	errC := history.CreateLoginAttempt(attempt)
+	if errC != nil {
+		log.Printf("could not record login of '%s': %s", attempt.Email, errC)
+	}
+
+	if attempt.Succeeded && attempt.UserID != nil {
+		errC = userRepo.UpdateUserLastLogin(*attempt.UserID, attempt.CreatedAt)
+		if errC != nil {
+			log.Printf("could not update last login of user %d: %s", *attempt.UserID, errC)
+		}
+	}
 }
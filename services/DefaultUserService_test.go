@@ -0,0 +1,776 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1360-64rc14/simple-api/authentication"
+	"github.com/d1360-64rc14/simple-api/config"
+	"github.com/d1360-64rc14/simple-api/dtos"
+	"github.com/d1360-64rc14/simple-api/hashing"
+	"github.com/d1360-64rc14/simple-api/interfaces"
+	"github.com/d1360-64rc14/simple-api/mocks"
+	"github.com/d1360-64rc14/simple-api/models"
+	"github.com/d1360-64rc14/simple-api/pagination"
+	"github.com/d1360-64rc14/simple-api/utils"
+)
+
+func newTestUserService(t *testing.T, algorithm string, userRepo *mocks.MockedUserRepository) *DefaultUserService {
+	settings := &config.Settings{
+		Auth: config.Auth{
+			BCryptCost: 4,
+			PasswordHashing: config.PasswordHashing{
+				Algorithm: algorithm,
+				Argon2id:  config.Argon2id{MemoryKiB: 1024, Iterations: 1, Parallelism: 1},
+			},
+		},
+	}
+
+	hasher, err := hashing.NewDefaultPasswordHasher(&settings.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursorSigner, err := pagination.NewHMACCursorSigner(&config.Pagination{Base64CursorKey: "Y3Vyc29ycy1vZi10aGUtdXNlcnMtbGlzdC1zaWduZXI"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator := mocks.NewMockedAuthenticator()
+	tokenService := NewDefaultTokenService(mocks.NewMockedRefreshTokenRepository(), mocks.NewMockedSessionRepository(), userRepo, mocks.NewMockedAPIKeyRepository(), authenticator, settings)
+	lockoutService := NewDefaultLockoutService(mocks.NewMockedLoginLockoutRepository(), settings)
+	loginHistoryRepo := mocks.NewMockedLoginHistoryRepository()
+	mfaService := NewDefaultMFAService(mocks.NewMockedMFARepository(), userRepo, loginHistoryRepo, authenticator, tokenService, lockoutService, settings)
+
+	service := NewDefaultUserService(
+		userRepo,
+		mocks.NewMockedUserTokenRepository(),
+		loginHistoryRepo,
+		authenticator,
+		tokenService,
+		hasher,
+		mfaService,
+		lockoutService,
+		mocks.NewMockedMailSender(),
+		cursorSigner,
+		settings,
+	)
+
+	return service.(*DefaultUserService)
+}
+
+func createTestUser(t *testing.T, service interfaces.UserService, email string, password string) *dtos.IdentifiedUser {
+	user, err := service.CreateUser(dtos.UserWithPassword{
+		UserModel: models.UserModel{UserName: strings.Split(email, "@")[0], Email: email},
+		Password:  password,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func TestCreateUser_PasswordLength(t *testing.T) {
+	testCases := []struct {
+		algorithm    string
+		length       int
+		expectedCode int
+	}{
+		{hashing.AlgorithmBCrypt, 72, 0},
+		{hashing.AlgorithmBCrypt, 73, http.StatusBadRequest},
+		{hashing.AlgorithmArgon2id, 128, 0},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			service := newTestUserService(t, _case.algorithm, mocks.NewMockedUserRepository())
+
+			_, err := service.CreateUser(dtos.UserWithPassword{
+				UserModel: models.UserModel{UserName: "diego", Email: "diego@mail.com"},
+				Password:  strings.Repeat("a", _case.length),
+			})
+			if _case.expectedCode == 0 {
+				if err != nil {
+					t.Errorf("Password of %d bytes should be accepted, got %v", _case.length, err)
+				}
+				return
+			}
+			if err == nil || err.Code() != _case.expectedCode {
+				t.Errorf("Code should be '%d', got '%v'", _case.expectedCode, err)
+			}
+		})
+	}
+}
+
+func TestLoginUser_RehashesOutdatedHash(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+
+	bcryptService := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	user := createTestUser(t, bcryptService, "diego@mail.com", "myPassword!")
+
+	argon2idService := newTestUserService(t, hashing.AlgorithmArgon2id, userRepo)
+
+	_, err := argon2idService.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "wrongPassword"})
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Fatalf("Wrong password should be rejected with 401, got %v", err)
+	}
+
+	hash, _ := userRepo.SelectUserHashFromId(user.ID)
+	if !strings.HasPrefix(hash, "$2a$") {
+		t.Fatalf("Failed login should not rehash, got '%s'", hash)
+	}
+
+	tokens, err := argon2idService.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.Token == "" || tokens.RefreshToken == "" {
+		t.Error("Login should return both tokens")
+	}
+
+	hash, _ = userRepo.SelectUserHashFromId(user.ID)
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		t.Fatalf("Successful login should rehash with argon2id, got '%s'", hash)
+	}
+
+	_, err = argon2idService.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!"})
+	if err != nil {
+		t.Errorf("Rehashed password should still log in, got %v", err)
+	}
+}
+
+func TestLoginUser_UniformFailures(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	createTestUser(t, service, "diego@mail.com", "myPassword!")
+
+	_, unknownErr := service.LoginUser(&dtos.LoginRequest{Email: "nobody@mail.com", Password: "myPassword!", ClientIP: "10.0.0.1"})
+	_, wrongErr := service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "wrongPassword", ClientIP: "10.0.0.1"})
+
+	if unknownErr == nil || wrongErr == nil {
+		t.Fatalf("Both logins should fail, got %v and %v", unknownErr, wrongErr)
+	}
+	if unknownErr.Code() != http.StatusUnauthorized || unknownErr.Error() != wrongErr.Error() {
+		t.Errorf("Unknown email and wrong password should fail alike with 401, got '%v' and '%v'", unknownErr, wrongErr)
+	}
+}
+
+func TestLoginUser_Lockout(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	createTestUser(t, service, "diego@mail.com", "myPassword!")
+
+	for i := 0; i < defaultLockoutAccountThreshold; i++ {
+		_, err := service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "wrongPassword", ClientIP: "10.0.0.1"})
+		if err == nil || err.Code() != http.StatusUnauthorized {
+			t.Fatalf("Wrong password should be rejected with 401, got %v", err)
+		}
+	}
+
+	_, err := service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!", ClientIP: "10.0.0.2"})
+	if err == nil || err.Code() != http.StatusTooManyRequests {
+		t.Fatalf("Locked out account should be rejected with 429 even with the right password, got %v", err)
+	}
+
+	if err := service.lockouts.ClearLockout(models.LockoutKindAccount, "diego@mail.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!", ClientIP: "10.0.0.2"})
+	if err != nil {
+		t.Errorf("Cleared lockout should allow logging in, got %v", err)
+	}
+}
+
+func TestLoginHistory(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	user := createTestUser(t, service, "diego@mail.com", "myPassword!")
+
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	logins := []*dtos.LoginRequest{
+		{Email: "nobody@mail.com", Password: "myPassword!", ClientIP: "10.0.0.1"},
+		{Email: "diego@mail.com", Password: "wrongPassword", ClientIP: "10.0.0.1", UserAgent: "curl/8.0"},
+		{Email: "diego@mail.com", Password: "myPassword!", ClientIP: "10.0.0.2", UserAgent: "Firefox"},
+	}
+	for _, login := range logins {
+		service.LoginUser(login)
+	}
+
+	selected, _ := service.SelectUserFromId(user.ID)
+	if selected.LastLoginAt == nil || !selected.LastLoginAt.Equal(now) {
+		t.Errorf("Last login should be %s, got %v", now, selected.LastLoginAt)
+	}
+
+	testCases := []struct {
+		query     dtos.LoginHistoryQuery
+		reasons   []string
+		succeeded []bool
+		next      bool
+		prev      bool
+	}{
+		{dtos.LoginHistoryQuery{}, []string{models.LoginReasonPassword, models.LoginReasonInvalidPassword}, []bool{true, false}, false, false},
+		{dtos.LoginHistoryQuery{Limit: 1}, []string{models.LoginReasonPassword}, []bool{true}, true, false},
+		{dtos.LoginHistoryQuery{Limit: 1, Offset: 1}, []string{models.LoginReasonInvalidPassword}, []bool{false}, false, true},
+		{dtos.LoginHistoryQuery{Limit: 1, Offset: 2}, []string{}, []bool{}, false, false},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			page, err := service.SelectUserLogins(user.ID, &_case.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if page.Total != 2 {
+				t.Errorf("Total should be '2', got '%d'", page.Total)
+			}
+			if (page.NextCursor != "") != _case.next || (page.PrevCursor != "") != _case.prev {
+				t.Errorf("Page should have next %t and prev %t, got '%s' and '%s'", _case.next, _case.prev, page.NextCursor, page.PrevCursor)
+			}
+			if len(page.Items) != len(_case.reasons) {
+				t.Fatalf("Page should have %d logins, got %d", len(_case.reasons), len(page.Items))
+			}
+			for j, attempt := range page.Items {
+				if attempt.Reason != _case.reasons[j] || attempt.Succeeded != _case.succeeded[j] {
+					t.Errorf("Login %d should be '%s' (%t), got '%s' (%t)", j, _case.reasons[j], _case.succeeded[j], attempt.Reason, attempt.Succeeded)
+				}
+			}
+		})
+	}
+
+	first, err := service.SelectUserLogins(user.ID, &dtos.LoginHistoryQuery{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextCursor, _ := service.cursors.Verify(first.NextCursor)
+
+	second, err := service.SelectUserLogins(user.ID, &dtos.LoginHistoryQuery{Limit: 1, Cursor: nextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Items) != 1 || second.Items[0].Reason != models.LoginReasonInvalidPassword || second.NextCursor != "" || second.PrevCursor == "" {
+		t.Fatalf("Second page should be the failed login with prev only, got %+v", second)
+	}
+	prevCursor, _ := service.cursors.Verify(second.PrevCursor)
+
+	back, err := service.SelectUserLogins(user.ID, &dtos.LoginHistoryQuery{Limit: 1, Cursor: prevCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Items) != 1 || back.Items[0].Reason != models.LoginReasonPassword || back.NextCursor == "" || back.PrevCursor != "" {
+		t.Fatalf("Going back should give the first page with next only, got %+v", back)
+	}
+
+	_, err = service.SelectUserLogins(user.ID, &dtos.LoginHistoryQuery{Cursor: &dtos.Cursor{Sort: "id", ID: 1}})
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Cursor of another list should give '%d', got '%v'", http.StatusBadRequest, err)
+	}
+
+	history := service.history.(*mocks.MockedLoginHistoryRepository)
+	if len(history.Attempts) != 3 || history.Attempts[0].UserID != nil || history.Attempts[0].Reason != models.LoginReasonUnknownEmail {
+		t.Errorf("Unknown emails should be recorded without user, got %+v", history.Attempts[0])
+	}
+	if history.Attempts[2].UserAgent != "Firefox" || history.Attempts[2].IP != "10.0.0.2" {
+		t.Errorf("Login should record its client, got %+v", history.Attempts[2])
+	}
+}
+
+func TestSelectUsers(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+
+	for _, email := range []string{"diego@mail.com", "ana@corp.com", "bruno@mail.com", "anabel@mail.com"} {
+		createTestUser(t, service, email, "myPassword!")
+	}
+
+	testCases := []struct {
+		query     dtos.UserQuery
+		usernames []string
+		total     int
+		next      bool
+		prev      bool
+	}{
+		{dtos.UserQuery{}, []string{"diego", "ana", "bruno", "anabel"}, 4, false, false},
+		{dtos.UserQuery{Limit: 2}, []string{"diego", "ana"}, 4, true, false},
+		{dtos.UserQuery{Limit: 2, Offset: 2}, []string{"bruno", "anabel"}, 4, false, true},
+		{dtos.UserQuery{Limit: 2, Offset: 1}, []string{"ana", "bruno"}, 4, true, true},
+		{dtos.UserQuery{Offset: 10}, []string{}, 4, false, false},
+		{dtos.UserQuery{Sort: "-id"}, []string{"anabel", "bruno", "ana", "diego"}, 4, false, false},
+		{dtos.UserQuery{Sort: "username"}, []string{"ana", "anabel", "bruno", "diego"}, 4, false, false},
+		{dtos.UserQuery{Sort: "-username", Limit: 1}, []string{"diego"}, 4, true, false},
+		{dtos.UserQuery{EmailDomain: "mail.com"}, []string{"diego", "bruno", "anabel"}, 3, false, false},
+		{dtos.UserQuery{EmailDomain: "MAIL.com", Sort: "username"}, []string{"anabel", "bruno", "diego"}, 3, false, false},
+		{dtos.UserQuery{EmailDomain: "corp"}, []string{}, 0, false, false},
+		{dtos.UserQuery{UsernamePrefix: "ana", Sort: "-username"}, []string{"anabel", "ana"}, 2, false, false},
+		{dtos.UserQuery{UsernamePrefix: "ana", EmailDomain: "mail.com"}, []string{"anabel"}, 1, false, false},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			page, err := service.SelectUsers(&_case.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if page.Total != _case.total {
+				t.Errorf("Total should be '%d', got '%d'", _case.total, page.Total)
+			}
+			if (page.NextCursor != "") != _case.next || (page.PrevCursor != "") != _case.prev {
+				t.Errorf("Page should have next %t and prev %t, got '%s' and '%s'", _case.next, _case.prev, page.NextCursor, page.PrevCursor)
+			}
+			if len(page.Items) != len(_case.usernames) {
+				t.Fatalf("Page should have %d users, got %d", len(_case.usernames), len(page.Items))
+			}
+			for j, user := range page.Items {
+				if user.UserName != _case.usernames[j] {
+					t.Errorf("User %d should be '%s', got '%s'", j, _case.usernames[j], user.UserName)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectUsers_Cursor(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+
+	for _, email := range []string{"diego@mail.com", "ana@mail.com", "bruno@mail.com", "carla@mail.com", "ana@corp.com"} {
+		createTestUser(t, service, email, "myPassword!")
+	}
+
+	scroll := func(query dtos.UserQuery) (*dtos.LinkedPage[*dtos.IdentifiedUser], []string) {
+		t.Helper()
+
+		page, err := service.SelectUsers(&query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		usernames := make([]string, len(page.Items))
+		for i, user := range page.Items {
+			usernames[i] = fmt.Sprintf("%s#%d", user.UserName, user.ID)
+		}
+
+		return page, usernames
+	}
+
+	cursor := func(token string) *dtos.Cursor {
+		t.Helper()
+
+		verified, err := service.cursors.Verify(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return verified
+	}
+
+	first, usernames := scroll(dtos.UserQuery{Sort: "username", Limit: 2})
+	if fmt.Sprint(usernames) != "[ana#1 ana#4]" || first.PrevCursor != "" {
+		t.Fatalf("First page should be [ana#1 ana#4] without prev, got %v and '%s'", usernames, first.PrevCursor)
+	}
+
+	// Users inserted before the cursor don't shift the following pages
+	createTestUser(t, service, "aaron@mail.com", "myPassword!")
+
+	second, usernames := scroll(dtos.UserQuery{Limit: 2, Cursor: cursor(first.NextCursor)})
+	if fmt.Sprint(usernames) != "[bruno#2 carla#3]" || second.NextCursor == "" || second.PrevCursor == "" {
+		t.Fatalf("Second page should be [bruno#2 carla#3] with next and prev, got %+v", second)
+	}
+
+	third, usernames := scroll(dtos.UserQuery{Limit: 2, Cursor: cursor(second.NextCursor)})
+	if fmt.Sprint(usernames) != "[diego#0]" || third.NextCursor != "" || third.Total != 6 {
+		t.Fatalf("Last page should be [diego#0] of 6 without next, got %+v", third)
+	}
+
+	back, usernames := scroll(dtos.UserQuery{Limit: 2, Cursor: cursor(third.PrevCursor)})
+	if fmt.Sprint(usernames) != "[bruno#2 carla#3]" || back.NextCursor == "" || back.PrevCursor == "" {
+		t.Fatalf("Going back should return [bruno#2 carla#3] with next and prev, got %+v", back)
+	}
+
+	back, usernames = scroll(dtos.UserQuery{Limit: 2, Cursor: cursor(back.PrevCursor)})
+	if fmt.Sprint(usernames) != "[ana#1 ana#4]" || back.PrevCursor == "" {
+		t.Fatalf("Going back again should return [ana#1 ana#4] with prev, got %+v", back)
+	}
+
+	back, usernames = scroll(dtos.UserQuery{Limit: 2, Cursor: cursor(back.PrevCursor)})
+	if fmt.Sprint(usernames) != "[aaron#5]" || back.PrevCursor != "" || back.NextCursor == "" {
+		t.Fatalf("First page should now be [aaron#5] with next only, got %+v", back)
+	}
+
+	descending, usernames := scroll(dtos.UserQuery{Sort: "-id", Limit: 3, EmailDomain: "mail.com"})
+	if fmt.Sprint(usernames) != "[aaron#5 carla#3 bruno#2]" {
+		t.Fatalf("Page should be [aaron#5 carla#3 bruno#2], got %v", usernames)
+	}
+
+	_, usernames = scroll(dtos.UserQuery{Limit: 3, EmailDomain: "mail.com", Cursor: cursor(descending.NextCursor)})
+	if fmt.Sprint(usernames) != "[ana#1 diego#0]" {
+		t.Errorf("Cursor should keep its sort, got %v", usernames)
+	}
+
+	_, err := service.SelectUsers(&dtos.UserQuery{Sort: "id", Cursor: cursor(descending.NextCursor)})
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Cursor of another sort should be rejected, got '%v'", err)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+
+	for _, email := range []string{"diego@mail.com", "ana@diego.dev", "anabel@mail.com", "bruno@corp.com", "ana.maria@corp.com"} {
+		createTestUser(t, service, email, "myPassword!")
+	}
+
+	testCases := []struct {
+		query     dtos.UserSearchQuery
+		usernames []string
+		respCode  int
+	}{
+		{dtos.UserSearchQuery{Q: "diego"}, []string{"diego", "ana"}, 0},
+		{dtos.UserSearchQuery{Q: "DIE"}, []string{"diego", "ana"}, 0},
+		{dtos.UserSearchQuery{Q: "ana"}, []string{"ana", "ana.maria", "anabel"}, 0},
+		{dtos.UserSearchQuery{Q: "ana", Limit: 2}, []string{"ana", "ana.maria"}, 0},
+		{dtos.UserSearchQuery{Q: "ana corp"}, []string{"ana.maria"}, 0},
+		{dtos.UserSearchQuery{Q: "ana@corp"}, []string{"ana.maria"}, 0},
+		{dtos.UserSearchQuery{Q: "mar"}, []string{"ana.maria"}, 0},
+		{dtos.UserSearchQuery{Q: "mail.com"}, []string{"anabel", "diego"}, 0},
+		{dtos.UserSearchQuery{Q: "iego"}, []string{}, 0},
+		{dtos.UserSearchQuery{Q: "nobody"}, []string{}, 0},
+		{dtos.UserSearchQuery{Q: "@."}, nil, http.StatusBadRequest},
+		{dtos.UserSearchQuery{Q: " "}, nil, http.StatusBadRequest},
+	}
+
+	for i, _case := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			users, err := service.SearchUsers(&_case.query)
+			if _case.respCode != 0 {
+				if err == nil || err.Code() != _case.respCode {
+					t.Errorf("Code should be '%d', got '%v'", _case.respCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(users) != len(_case.usernames) {
+				t.Fatalf("Search should find %d users, got %d", len(_case.usernames), len(users))
+			}
+			for j, user := range users {
+				if user.UserName != _case.usernames[j] {
+					t.Errorf("User %d should be '%s', got '%s'", j, _case.usernames[j], user.UserName)
+				}
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	user := createTestUser(t, service, "diego@mail.com", "myPassword!")
+
+	err := service.ChangePassword(user.ID, &dtos.PasswordChange{CurrentPassword: "wrongPassword", NewPassword: "myNewPassword!"})
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Fatalf("Wrong current password should be rejected with 401, got %v", err)
+	}
+
+	err = service.ChangePassword(user.ID, &dtos.PasswordChange{CurrentPassword: "myPassword!", NewPassword: "myNewPassword!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !service.auth.(*mocks.MockedAuthenticator).RevokedUsers[user.ID] {
+		t.Error("Changing the password should revoke the user tokens")
+	}
+
+	_, err = service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!"})
+	if err == nil || err.Code() != http.StatusUnauthorized {
+		t.Errorf("Old password should be rejected with 401, got %v", err)
+	}
+
+	_, err = service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myNewPassword!"})
+	if err != nil {
+		t.Errorf("New password should log in, got %v", err)
+	}
+}
+
+var mailedTokenRegexp = regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{43}$`)
+
+func mailedToken(t *testing.T, mailSender *mocks.MockedMailSender, address string) string {
+	mail := mailSender.LastTo(address)
+	if mail == nil {
+		t.Fatalf("No mail was sent to '%s'", address)
+	}
+
+	token := mailedTokenRegexp.FindString(mail.Body)
+	if token == "" {
+		t.Fatalf("Mail to '%s' has no token: %s", address, mail.Body)
+	}
+
+	return token
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, mocks.NewMockedUserRepository())
+
+	err := service.ForgotPassword(&dtos.PasswordForgot{Email: "nobody@mail.com"})
+	if err != nil {
+		t.Fatalf("Unknown email should not be reported, got %v", err)
+	}
+
+	if sent := service.mail.(*mocks.MockedMailSender).Sent; len(sent) != 0 {
+		t.Errorf("No mail should be sent, got %d", len(sent))
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	service.now = func() time.Time { return now }
+	mailSender := service.mail.(*mocks.MockedMailSender)
+
+	user := createTestUser(t, service, "diego@mail.com", "myPassword!")
+
+	if err := service.ForgotPassword(&dtos.PasswordForgot{Email: "diego@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	replacedToken := mailedToken(t, mailSender, "diego@mail.com")
+
+	if err := service.ForgotPassword(&dtos.PasswordForgot{Email: "diego@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	expiredToken := mailedToken(t, mailSender, "diego@mail.com")
+
+	testCases := []struct {
+		Token        string
+		After        time.Duration
+		ExpectedCode int
+	}{
+		{"notAToken", 0, http.StatusBadRequest},
+		{replacedToken, 0, http.StatusBadRequest},
+		{expiredToken, 2 * time.Hour, http.StatusBadRequest},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			service.now = func() time.Time { return now.Add(testCase.After) }
+
+			err := service.ResetPassword(&dtos.PasswordReset{Token: testCase.Token, NewPassword: "myNewPassword!"})
+			if err == nil || err.Code() != testCase.ExpectedCode {
+				t.Errorf("Code should be '%d', got '%v'", testCase.ExpectedCode, err)
+			}
+		})
+	}
+
+	service.now = func() time.Time { return now }
+
+	if err := service.ForgotPassword(&dtos.PasswordForgot{Email: "diego@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	token := mailedToken(t, mailSender, "diego@mail.com")
+
+	err := service.ResetPassword(&dtos.PasswordReset{Token: token, NewPassword: strings.Repeat("a", 73)})
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Password too long for bcrypt should be rejected with 400, got %v", err)
+	}
+
+	if err := service.ResetPassword(&dtos.PasswordReset{Token: token, NewPassword: "myNewPassword!"}); err != nil {
+		t.Fatalf("Refused password should not consume the token, got %v", err)
+	}
+
+	err = service.ResetPassword(&dtos.PasswordReset{Token: token, NewPassword: "anotherPassword!"})
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Reset token should be single-use, got %v", err)
+	}
+
+	if !service.auth.(*mocks.MockedAuthenticator).RevokedUsers[user.ID] {
+		t.Error("Resetting the password should revoke the user tokens")
+	}
+
+	_, err = service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myNewPassword!"})
+	if err != nil {
+		t.Errorf("New password should log in, got %v", err)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	service.settings.Auth.RequireVerifiedEmail = true
+	mailSender := service.mail.(*mocks.MockedMailSender)
+
+	createTestUser(t, service, "diego@mail.com", "myPassword!")
+	firstToken := mailedToken(t, mailSender, "diego@mail.com")
+
+	login := &dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!"}
+
+	_, err := service.LoginUser(login)
+	if err == nil || err.Code() != http.StatusForbidden {
+		t.Fatalf("Unverified user login should be rejected with 403, got %v", err)
+	}
+
+	if err := service.ResendVerification(&dtos.VerificationResend{Email: "diego@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	token := mailedToken(t, mailSender, "diego@mail.com")
+
+	err = service.VerifyEmail(firstToken)
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("Resending should invalidate the previous token, got %v", err)
+	}
+
+	if err := service.VerifyEmail(token); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.LoginUser(login); err != nil {
+		t.Errorf("Verified user should log in, got %v", err)
+	}
+
+	sentCount := len(mailSender.Sent)
+
+	if err := service.ResendVerification(&dtos.VerificationResend{Email: "diego@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mailSender.Sent) != sentCount {
+		t.Error("Verified user should not receive another verification mail")
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	mailSender := service.mail.(*mocks.MockedMailSender)
+
+	user := createTestUser(t, service, "diego@mail.com", "myPassword!")
+	createTestUser(t, service, "taken@mail.com", "myPassword!")
+
+	testCases := []struct {
+		Email        string
+		ExpectedCode int
+	}{
+		{"diego@mail.com", http.StatusBadRequest},
+		{"taken@mail.com", http.StatusConflict},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("case_%d", i), func(t *testing.T) {
+			err := service.RequestEmailChange(user.ID, &dtos.EmailChange{Email: testCase.Email})
+			if err == nil || err.Code() != testCase.ExpectedCode {
+				t.Errorf("Code should be '%d', got '%v'", testCase.ExpectedCode, err)
+			}
+		})
+	}
+
+	if err := service.RequestEmailChange(user.ID, &dtos.EmailChange{Email: "late@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	lateToken := mailedToken(t, mailSender, "late@mail.com")
+
+	if err := service.RequestEmailChange(user.ID, &dtos.EmailChange{Email: "new@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	token := mailedToken(t, mailSender, "new@mail.com")
+
+	if _, err := service.LoginUser(&dtos.LoginRequest{Email: "diego@mail.com", Password: "myPassword!"}); err != nil {
+		t.Errorf("Current email should keep working until confirmed, got %v", err)
+	}
+
+	err := service.ConfirmEmailChange(lateToken)
+	if err == nil || err.Code() != http.StatusBadRequest {
+		t.Errorf("A newer request should invalidate the previous token, got %v", err)
+	}
+
+	if err := service.ConfirmEmailChange(token); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, _ := userRepo.SelectUserFromId(user.ID)
+	if updated.Email != "new@mail.com" || !updated.EmailVerified {
+		t.Errorf("Email should be the verified 'new@mail.com', got '%s' (verified: %t)", updated.Email, updated.EmailVerified)
+	}
+
+	if mail := mailSender.LastTo("diego@mail.com"); mail == nil || !strings.Contains(mail.Body, "new@mail.com") {
+		t.Error("Previous email address should be notified of the change")
+	}
+
+	if !service.auth.(*mocks.MockedAuthenticator).RevokedUsers[user.ID] {
+		t.Error("Changing the email should revoke the user tokens")
+	}
+
+	if _, err := service.LoginUser(&dtos.LoginRequest{Email: "new@mail.com", Password: "myPassword!"}); err != nil {
+		t.Errorf("New email should log in, got %v", err)
+	}
+}
+
+func TestAPIKeys_RevokedOnCredentialsReset(t *testing.T) {
+	userRepo := mocks.NewMockedUserRepository()
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, userRepo)
+	user := createTestUser(t, service, "diego@mail.com", "myPassword!")
+
+	apiKeyRepo := service.tokens.(*DefaultTokenService).apiKeys
+	apiKeyRepo.CreateAPIKey(&dtos.APIKey{UserID: user.ID, Name: "ci", Hash: utils.HashToken("sak_ci")})
+
+	authenticator := authentication.NewAPIKeyAuthenticator(service.auth, apiKeyRepo, userRepo)
+	service.auth = authenticator
+
+	if err := service.GrantRole(user.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RevokeRole(user.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticator.ParseToken("sak_ci"); err != nil {
+		t.Errorf("Revoking a role should keep the API keys, got '%v'", err)
+	}
+
+	if err := service.RequestEmailChange(user.ID, &dtos.EmailChange{Email: "new@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.ConfirmEmailChange(mailedToken(t, service.mail.(*mocks.MockedMailSender), "new@mail.com")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticator.ParseToken("sak_ci"); err != nil {
+		t.Errorf("Changing the email should keep the API keys, got '%v'", err)
+	}
+
+	err := service.ChangePassword(user.ID, &dtos.PasswordChange{CurrentPassword: "myPassword!", NewPassword: "myNewPassword!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticator.ParseToken("sak_ci"); !errors.Is(err, utils.ErrTokenRevoked) {
+		t.Errorf("Changing the password should revoke the API keys, got '%v'", err)
+	}
+}
+
+func TestEmailChange_TakenBeforeConfirmation(t *testing.T) {
+	service := newTestUserService(t, hashing.AlgorithmBCrypt, mocks.NewMockedUserRepository())
+
+	user := createTestUser(t, service, "diego@mail.com", "myPassword!")
+
+	if err := service.RequestEmailChange(user.ID, &dtos.EmailChange{Email: "new@mail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	token := mailedToken(t, service.mail.(*mocks.MockedMailSender), "new@mail.com")
+
+	createTestUser(t, service, "new@mail.com", "myPassword!")
+
+	err := service.ConfirmEmailChange(token)
+	if err == nil || err.Code() != http.StatusConflict {
+		t.Errorf("Code should be '%d', got '%v'", http.StatusConflict, err)
+	}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewRandomToken returns a URL safe string built from size random bytes.
+func NewRandomToken(size int) (string, error) {
+	token := make([]byte, size)
+
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a random token.
+//
+// Only suited for high entropy secrets, never for user passwords.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "errors"
+
+// Reasons for an Authenticator to reject a token, to be matched with errors.Is.
+var (
+	ErrTokenMalformed    = errors.New("token is malformed")
+	ErrTokenBadSignature = errors.New("token signature is invalid")
+	ErrTokenExpired      = errors.New("token is expired")
+	ErrTokenRevoked      = errors.New("token has been revoked")
+	ErrTokenInvalid      = errors.New("token is invalid")
+)
+
+// IsTokenError checks if err is one of the token rejection reasons.
+func IsTokenError(err error) bool {
+	return errors.Is(err, ErrTokenMalformed) ||
+		errors.Is(err, ErrTokenBadSignature) ||
+		errors.Is(err, ErrTokenExpired) ||
+		errors.Is(err, ErrTokenRevoked) ||
+		errors.Is(err, ErrTokenInvalid)
+}